@@ -2,50 +2,496 @@ package redis_client
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
 	"github.com/pkg/errors"
 	"io"
 	"net"
+	"strconv"
+	"strings"
 	"time"
 )
 
+const (
+	defaultTimeout = time.Second * 5
+)
+
 var (
 	_ io.Closer = &Client{}
+
+	// ErrBrokenConnection is returned when using a client after a command failed halfway, as there is no way
+	// to know what is still waiting to be read on the connection it can't be used anymore.
+	ErrBrokenConnection = errors.New("redis: connection is broken and can't be reused")
+
+	aLongTimeAgo = time.Unix(1, 0)
 )
 
+// ServerInfo is what the server tells us about itself when replying to `HELLO`.
+type ServerInfo struct {
+	Server  string
+	Version string
+	Proto   int64
+	ID      int64
+	Mode    string
+	Role    string
+	Modules []ModuleInfo
+}
+
+// ModuleInfo is a module loaded in the server as listed on the `HELLO` reply.
+type ModuleInfo struct {
+	Name    string
+	Version int64
+}
+
 type Client struct {
-	conn   net.Conn
-	reader *Reader
-	writer *Writer
+	Commands
+	conn     net.Conn
+	reader   *Reader
+	writer   *Writer
+	protocol int
+	info     *ServerInfo
+	options  Options
+	broken   bool
+	// createdAt and usedAt are only set for connections managed by a Pool
+	createdAt time.Time
+	usedAt    time.Time
 }
 
 func (c *Client) Close() error {
 	return c.conn.Close()
 }
 
+// Protocol returns the RESP version this connection is using, either 2 or 3.
+func (c *Client) Protocol() int {
+	return c.protocol
+}
+
+// ServerInfo returns the server details sent on the `HELLO` reply, it is nil if the connection
+// did not negotiate the protocol with `HELLO`.
+func (c *Client) ServerInfo() *ServerInfo {
+	return c.info
+}
+
+// Send executes a command with the default timeout, it is the same as calling Do with a background context.
 func (c *Client) Send(values []interface{}) (*Result, error) {
-	c.conn.SetDeadline(time.Now().Add(time.Second * 5))
+	return c.Do(context.Background(), values...)
+}
+
+// Do executes a command and waits for its reply. the deadline comes from the context if it has one, otherwise
+// the default timeout is used (plus the time the command itself blocks for blocking commands like BLPOP or
+// XREAD BLOCK). cancelling the context interrupts a command that is waiting for a reply. if a command fails
+// halfway the connection can't be trusted anymore, it is marked as broken and all calls after that fail.
+func (c *Client) Do(ctx context.Context, args ...interface{}) (*Result, error) {
+	var result *Result
+	err := c.execute(ctx, args, func() error {
+		var err error
+		result, err = c.reader.Read()
+		return err
+	})
+
+	return result, err
+}
+
+// SendBulkTo sends a command that replies with a bulk string, like GET, and copies the value to w as it is
+// read from the connection instead of loading it all in memory.
+func (c *Client) SendBulkTo(values []interface{}, w io.Writer) (int64, error) {
+	return c.DoBulkTo(context.Background(), w, values...)
+}
+
+// DoBulkTo is the same as SendBulkTo but the deadline and cancellation come from the context like in Do.
+func (c *Client) DoBulkTo(ctx context.Context, w io.Writer, args ...interface{}) (int64, error) {
+	var (
+		written  int64
+		replyErr error
+	)
+
+	err := c.execute(ctx, args, func() error {
+		var err error
+		written, replyErr, err = c.reader.readBulkTo(w)
+		return err
+	})
+
+	if err != nil {
+		return written, err
+	}
+
+	return written, replyErr
+}
+
+// Broken returns true if a previous command failed in a way that left the connection in an unknown state.
+func (c *Client) Broken() bool {
+	return c.broken
+}
+
+// execute writes the command and calls read to read its reply, setting the deadlines and marking the
+// connection as broken if anything fails on the way.
+func (c *Client) execute(ctx context.Context, args []interface{}, read func() error) error {
+	if len(args) == 0 {
+		return errors.New("no command given")
+	}
+
+	return c.executeAll(ctx, [][]interface{}{args}, read)
+}
+
+// executeAll writes all commands in a single flush and then calls read to read their replies.
+func (c *Client) executeAll(ctx context.Context, commands [][]interface{}, read func() error) error {
+	if c.broken {
+		return ErrBrokenConnection
+	}
 
-	if err := c.writer.WriteArray(values); err != nil {
-		return nil, errors.Wrapf(err, "failed to execute operation: %v", values[0])
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	return c.reader.Read()
+	readDeadline, writeDeadline := c.deadlines(ctx, commands)
+	c.conn.SetReadDeadline(readDeadline)
+	c.conn.SetWriteDeadline(writeDeadline)
+
+	stop := c.watch(ctx)
+	err := c.write(commands)
+	if err == nil {
+		err = read()
+	}
+	stop()
+
+	if err != nil {
+		c.broken = true
+
+		if ctxErr := contextError(ctx, err); ctxErr != nil {
+			return errors.Wrapf(ctxErr, "operation %v interrupted", commands[0][0])
+		}
+	}
+
+	return err
+}
+
+// contextError returns the context error if the context is the reason the operation failed. the connection
+// deadline is the same as the context deadline so the connection can time out before the context notices it.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	var netErr net.Error
+	if deadline, ok := ctx.Deadline(); ok && errors.As(err, &netErr) && netErr.Timeout() && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+
+	return nil
+}
+
+func (c *Client) write(commands [][]interface{}) error {
+	for _, args := range commands {
+		if err := c.writer.WriteCommand(args...); err != nil {
+			return errors.Wrapf(err, "failed to execute operation: %v", args[0])
+		}
+	}
+
+	if err := c.writer.Flush(); err != nil {
+		return errors.Wrapf(err, "failed to execute operation: %v", commands[0][0])
+	}
+
+	return nil
+}
+
+// deadlines returns the read and write deadlines for commands, the context deadline wins over everything else.
+// a zero time means there is no deadline at all, which is what happens with commands that block forever.
+func (c *Client) deadlines(ctx context.Context, commands [][]interface{}) (time.Time, time.Time) {
+	if deadline, ok := ctx.Deadline(); ok {
+		return deadline, deadline
+	}
+
+	now := time.Now()
+	writeDeadline := now.Add(c.options.writeTimeout())
+
+	longest := time.Duration(0)
+	for _, args := range commands {
+		blocking, isBlocking := blockingTimeout(args)
+		if isBlocking && blocking == 0 {
+			return time.Time{}, writeDeadline
+		}
+
+		if blocking > longest {
+			longest = blocking
+		}
+	}
+
+	return now.Add(c.options.readTimeout() + longest), writeDeadline
+}
+
+// watch interrupts any blocked read or write on the connection once the context is done, the returned
+// function must be called once the operation is over.
+func (c *Client) watch(ctx context.Context) func() {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		select {
+		case <-ctx.Done():
+			c.conn.SetDeadline(aLongTimeAgo)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
 }
 
 func Connect(ctx context.Context, address string) (*Client, error) {
+	return ConnectWithOptions(ctx, Options{
+		Address: address,
+	})
+}
+
+// ConnectWithOptions connects to the server, negotiates the protocol, authenticates and selects the database.
+func ConnectWithOptions(ctx context.Context, options Options) (*Client, error) {
 	dialer := net.Dialer{
-		Timeout:   time.Second * 5,
-		KeepAlive: time.Second * 10,
+		Timeout:   options.dialTimeout(),
+		KeepAlive: defaultKeepAlive,
+	}
+
+	conn, err := dialer.DialContext(ctx, options.network(), options.Address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to %v", options.Address)
+	}
+
+	if options.TLSConfig != nil {
+		conn, err = handshakeTLS(ctx, conn, options)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to establish TLS connection to %v", options.Address)
+		}
+	}
+
+	client := &Client{
+		conn:     conn,
+		reader:   NewReaderLimit(conn, options.maxBulkLength()),
+		writer:   NewWriter(conn),
+		protocol: 2,
+		options:  options,
+	}
+	client.Commands = Commands{do: client.Do}
+
+	if err := client.handshake(ctx, options); err != nil {
+		client.Close()
+		return nil, errors.Wrapf(err, "failed to initialize connection to %v", options.Address)
+	}
+
+	return client, nil
+}
+
+func handshakeTLS(ctx context.Context, conn net.Conn, options Options) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, options.dialTimeout())
+	defer cancel()
+
+	tlsConn := tls.Client(conn, options.tlsConfig())
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// handshake negotiates the protocol and authenticates the connection. servers older than redis 6 don't
+// know `HELLO` so if it fails we go back to RESP2 and use `AUTH` and `CLIENT SETNAME` instead.
+func (c *Client) handshake(ctx context.Context, options Options) error {
+	if options.Protocol == 3 {
+		args := []interface{}{"HELLO", "3"}
+		if options.Password != "" {
+			username := options.Username
+			if username == "" {
+				username = "default"
+			}
+			args = append(args, "AUTH", username, options.Password)
+		}
+
+		if options.ClientName != "" {
+			args = append(args, "SETNAME", options.ClientName)
+		}
+
+		result, err := c.Do(ctx, args...)
+		if err != nil {
+			return err
+		}
+
+		if result.Err() == nil {
+			info, err := parseServerInfo(result)
+			if err != nil {
+				return err
+			}
+
+			c.info = info
+			c.protocol = int(info.Proto)
+			return c.selectDatabase(ctx, options)
+		}
+	}
+
+	if options.Password != "" {
+		args := []interface{}{"AUTH", options.Password}
+		if options.Username != "" {
+			args = []interface{}{"AUTH", options.Username, options.Password}
+		}
+
+		if err := c.doOK(ctx, args); err != nil {
+			return err
+		}
+	}
+
+	if options.ClientName != "" {
+		if err := c.doOK(ctx, []interface{}{"CLIENT", "SETNAME", options.ClientName}); err != nil {
+			return err
+		}
+	}
+
+	return c.selectDatabase(ctx, options)
+}
+
+func (c *Client) selectDatabase(ctx context.Context, options Options) error {
+	if options.Database == 0 {
+		return nil
 	}
 
-	conn, err := dialer.DialContext(ctx, "tcp4", address)
+	return c.doOK(ctx, []interface{}{"SELECT", strconv.Itoa(options.Database)})
+}
+
+// doOK executes a command that only replies with OK or an error.
+func (c *Client) doOK(ctx context.Context, values []interface{}) error {
+	result, err := c.Do(ctx, values...)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to connect to %v", address)
+		return err
+	}
+
+	if err := result.Err(); err != nil {
+		return errors.Wrapf(err, "%v failed", values[0])
+	}
+
+	return nil
+}
+
+// parseServerInfo reads the `HELLO` reply, a map on RESP3 or a flat list of keys and values on RESP2.
+func parseServerInfo(result *Result) (*ServerInfo, error) {
+	entries, err := pairs(result.Content())
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid HELLO reply")
+	}
+
+	info := &ServerInfo{}
+	for _, entry := range entries {
+		switch entry.Key {
+		case "server":
+			info.Server, _ = entry.Value.(string)
+		case "version":
+			info.Version, _ = entry.Value.(string)
+		case "proto":
+			info.Proto, _ = entry.Value.(int64)
+		case "id":
+			info.ID, _ = entry.Value.(int64)
+		case "mode":
+			info.Mode, _ = entry.Value.(string)
+		case "role":
+			info.Role, _ = entry.Value.(string)
+		case "modules":
+			modules, _ := entry.Value.([]interface{})
+			for _, m := range modules {
+				fields, err := pairs(m)
+				if err != nil {
+					return nil, errors.Wrap(err, "invalid module on HELLO reply")
+				}
+
+				module := ModuleInfo{}
+				for _, field := range fields {
+					switch field.Key {
+					case "name":
+						module.Name, _ = field.Value.(string)
+					case "ver":
+						module.Version, _ = field.Value.(int64)
+					}
+				}
+
+				info.Modules = append(info.Modules, module)
+			}
+		}
+	}
+
+	if info.Proto == 0 {
+		return nil, fmt.Errorf("HELLO reply does not include the protocol version: %#v", result.Content())
+	}
+
+	return info, nil
+}
+
+// pairs turns either a RESP3 map or a RESP2 flat array of keys and values into map entries.
+func pairs(content interface{}) (Map, error) {
+	switch t := content.(type) {
+	case Map:
+		return t, nil
+	case []interface{}:
+		if len(t)%2 != 0 {
+			return nil, fmt.Errorf("array has an odd number of items (%v) and can't be read as keys and values", len(t))
+		}
+
+		entries := make(Map, 0, len(t)/2)
+		for x := 0; x < len(t); x += 2 {
+			entries = append(entries, MapEntry{Key: t[x], Value: t[x+1]})
+		}
+
+		return entries, nil
+	}
+
+	return nil, fmt.Errorf("content is not a map: %#v", content)
+}
+
+// blockingTimeout returns how long a blocking command can block for, the second return is false if the
+// command isn't a blocking one. a zero timeout on a blocking command means it blocks forever.
+func blockingTimeout(args []interface{}) (time.Duration, bool) {
+	command := strings.ToUpper(fmt.Sprint(args[0]))
+	switch command {
+	case "BLPOP", "BRPOP", "BRPOPLPUSH", "BLMOVE", "BZPOPMIN", "BZPOPMAX":
+		// the timeout is the last argument, in seconds
+		if len(args) < 2 {
+			return 0, false
+		}
+
+		return seconds(args[len(args)-1]), true
+	case "BLMPOP", "BZMPOP":
+		// the timeout is the first argument, in seconds
+		if len(args) < 2 {
+			return 0, false
+		}
+
+		return seconds(args[1]), true
+	case "XREAD", "XREADGROUP":
+		// only blocking if there is a BLOCK option, in milliseconds
+		for x := 1; x < len(args)-1; x++ {
+			if strings.EqualFold(fmt.Sprint(args[x]), "BLOCK") {
+				return seconds(args[x+1]) / 1000, true
+			}
+		}
+	case "WAIT":
+		// WAIT numreplicas timeout, in milliseconds
+		if len(args) == 3 {
+			return seconds(args[2]) / 1000, true
+		}
+	}
+
+	return 0, false
+}
+
+// seconds parses a command argument as a number of seconds, invalid values are ignored as the server
+// will refuse the command anyway.
+func seconds(arg interface{}) time.Duration {
+	value, err := strconv.ParseFloat(fmt.Sprint(arg), 64)
+	if err != nil || value < 0 {
+		return 0
 	}
 
-	return &Client{
-		conn:   conn,
-		reader: NewReader(conn),
-		writer: NewWriter(conn),
-	}, nil
+	return time.Duration(value * float64(time.Second))
 }
@@ -1,11 +1,27 @@
 package redis_client
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
 	"github.com/alicebob/miniredis/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type command struct {
@@ -13,6 +29,21 @@ type command struct {
 	output interface{}
 }
 
+// newTestClient connects to a new miniredis server, both are closed once the test is done.
+func newTestClient(t *testing.T) (*Client, *miniredis.Miniredis) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(server.Close)
+
+	client, err := Connect(context.Background(), server.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	return client, server
+}
+
 func TestConnect(t *testing.T) {
 	tt := []struct {
 		name     string
@@ -93,3 +124,626 @@ func TestConnect(t *testing.T) {
 		})
 	}
 }
+
+func TestConnectWithOptions_Hello(t *testing.T) {
+	tt := []struct {
+		name     string
+		password string
+		options  Options
+		protocol int
+		info     *ServerInfo
+		err      string
+	}{
+		{
+			name:     "without HELLO",
+			options:  Options{},
+			protocol: 2,
+		},
+		{
+			name: "with HELLO 3",
+			options: Options{
+				Protocol:   3,
+				ClientName: "some-client",
+			},
+			protocol: 3,
+			info: &ServerInfo{
+				Server:  "miniredis",
+				Version: "6.0.5",
+				Proto:   3,
+				ID:      42,
+				Mode:    "standalone",
+				Role:    "master",
+			},
+		},
+		{
+			name:     "with HELLO 3 and a password",
+			password: "some-password",
+			options: Options{
+				Protocol: 3,
+				Password: "some-password",
+			},
+			protocol: 3,
+			info: &ServerInfo{
+				Server:  "miniredis",
+				Version: "6.0.5",
+				Proto:   3,
+				ID:      42,
+				Mode:    "standalone",
+				Role:    "master",
+			},
+		},
+		{
+			name:     "with AUTH on RESP2",
+			password: "some-password",
+			options: Options{
+				Password: "some-password",
+			},
+			protocol: 2,
+		},
+		{
+			name:     "with the wrong password",
+			password: "some-password",
+			options: Options{
+				Protocol: 3,
+				Password: "other-password",
+			},
+			err: "AUTH failed: WRONGPASS invalid username-password pair",
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			server, err := miniredis.Run()
+			require.NoError(t, err)
+			defer server.Close()
+
+			if ts.password != "" {
+				server.RequireAuth(ts.password)
+			}
+
+			ts.options.Address = server.Addr()
+			client, err := ConnectWithOptions(context.Background(), ts.options)
+			if ts.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), ts.err)
+				return
+			}
+
+			require.NoError(t, err)
+			defer client.Close()
+
+			assert.Equal(t, ts.protocol, client.Protocol())
+			assert.Equal(t, ts.info, client.ServerInfo())
+
+			result, err := client.Send([]interface{}{"PING"})
+			require.NoError(t, err)
+			assert.Equal(t, "PONG", result.Content())
+		})
+	}
+}
+
+func TestConnectWithOptions_HelloReturnsMaps(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	server.HSet("some-hash", "field", "value")
+
+	client, err := ConnectWithOptions(context.Background(), Options{Address: server.Addr(), Protocol: 3})
+	require.NoError(t, err)
+	defer client.Close()
+
+	result, err := client.Send([]interface{}{"HGETALL", "some-hash"})
+	require.NoError(t, err)
+	assert.Equal(t, Map{{Key: "field", Value: "value"}}, result.Content())
+}
+
+func TestConnectWithOptions_HelloFallback(t *testing.T) {
+	server := newFakeServer(t, func(w io.Writer, args []string) {
+		switch strings.ToUpper(args[0]) {
+		case "HELLO":
+			io.WriteString(w, "-ERR unknown command 'HELLO'\r\n")
+		case "AUTH":
+			io.WriteString(w, "+OK\r\n")
+		case "CLIENT":
+			io.WriteString(w, "+OK\r\n")
+		default:
+			io.WriteString(w, "+PONG\r\n")
+		}
+	})
+
+	client, err := ConnectWithOptions(context.Background(), Options{
+		Address:    server.Addr(),
+		Protocol:   3,
+		Password:   "some-password",
+		ClientName: "some-client",
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	assert.Equal(t, 2, client.Protocol())
+	assert.Nil(t, client.ServerInfo())
+	assert.Equal(t, [][]string{
+		{"HELLO", "3", "AUTH", "default", "some-password", "SETNAME", "some-client"},
+		{"AUTH", "some-password"},
+		{"CLIENT", "SETNAME", "some-client"},
+	}, server.Commands())
+}
+
+// fakeServer is a tiny RESP server for the behaviours miniredis doesn't cover. the handler gets every command
+// sent by clients and writes whatever reply it wants to the connection.
+type fakeServer struct {
+	listener net.Listener
+	handler  func(w io.Writer, args []string)
+	mutex    sync.Mutex
+	commands [][]string
+}
+
+func newFakeServer(t *testing.T, handler func(w io.Writer, args []string)) *fakeServer {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	return newFakeServerWithListener(t, listener, handler)
+}
+
+func newFakeServerWithListener(t *testing.T, listener net.Listener, handler func(w io.Writer, args []string)) *fakeServer {
+	server := &fakeServer{
+		listener: listener,
+		handler:  handler,
+	}
+
+	go server.serve()
+
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	return server
+}
+
+func (s *fakeServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Commands returns all commands the server has received so far.
+func (s *fakeServer) Commands() [][]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([][]string{}, s.commands...)
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.serveConn(conn)
+	}
+}
+
+func (s *fakeServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	reader := NewReader(conn)
+	for {
+		result, err := reader.Read()
+		if err != nil {
+			return
+		}
+
+		values, err := result.Slice()
+		if err != nil {
+			return
+		}
+
+		args := make([]string, 0, len(values))
+		for _, v := range values {
+			args = append(args, fmt.Sprint(v))
+		}
+
+		s.mutex.Lock()
+		s.commands = append(s.commands, args)
+		s.mutex.Unlock()
+
+		s.handler(conn, args)
+	}
+}
+
+func TestClient_SendBulkTo(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	value := strings.Repeat("0123456789", 200*1024)
+	require.NoError(t, server.Set("some-key", value))
+
+	client, err := Connect(context.Background(), server.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+
+	buffer := &bytes.Buffer{}
+	written, err := client.SendBulkTo([]interface{}{"GET", "some-key"}, buffer)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(len(value)), written)
+	assert.Equal(t, value, buffer.String())
+
+	_, err = client.SendBulkTo([]interface{}{"GET", "other-key"}, buffer)
+	assert.Equal(t, ErrNil, err)
+}
+
+func TestClient_Do(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	client, err := Connect(context.Background(), server.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+
+	result, err := client.Do(context.Background(), "SET", "some-key", "some-value")
+	require.NoError(t, err)
+	assert.Equal(t, "OK", result.Content())
+
+	result, err = client.Do(context.Background(), "GET", "some-key")
+	require.NoError(t, err)
+	assert.Equal(t, "some-value", result.Content())
+	assert.False(t, client.Broken())
+}
+
+func TestClient_DoInterrupted(t *testing.T) {
+	tt := []struct {
+		name    string
+		context func() (context.Context, context.CancelFunc)
+		err     error
+	}{
+		{
+			name: "context is cancelled",
+			context: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(time.Millisecond*50, cancel)
+				return ctx, cancel
+			},
+			err: context.Canceled,
+		},
+		{
+			name: "context deadline is exceeded",
+			context: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), time.Millisecond*50)
+			},
+			err: context.DeadlineExceeded,
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			server, err := miniredis.Run()
+			require.NoError(t, err)
+			defer server.Close()
+
+			client, err := Connect(context.Background(), server.Addr())
+			require.NoError(t, err)
+			defer client.Close()
+
+			ctx, cancel := ts.context()
+			defer cancel()
+
+			_, err = client.Do(ctx, "BLPOP", "some-list", 0)
+			assert.True(t, errors.Is(err, ts.err), "error should be %v but was %v", ts.err, err)
+			assert.True(t, client.Broken())
+
+			_, err = client.Do(context.Background(), "PING")
+			assert.Equal(t, ErrBrokenConnection, err)
+		})
+	}
+}
+
+func TestClient_DoCancelledBeforeStarting(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	client, err := Connect(context.Background(), server.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = client.Do(ctx, "PING")
+	assert.Equal(t, context.Canceled, err)
+	assert.False(t, client.Broken())
+}
+
+func TestBlockingTimeout(t *testing.T) {
+	tt := []struct {
+		args     []interface{}
+		timeout  time.Duration
+		blocking bool
+	}{
+		{
+			args: []interface{}{"GET", "some-key"},
+		},
+		{
+			args:     []interface{}{"BLPOP", "list-a", "list-b", 10},
+			timeout:  time.Second * 10,
+			blocking: true,
+		},
+		{
+			args:     []interface{}{"brpop", "list-a", "0.5"},
+			timeout:  time.Millisecond * 500,
+			blocking: true,
+		},
+		{
+			args:     []interface{}{"BLPOP", "list-a", 0},
+			blocking: true,
+		},
+		{
+			args:     []interface{}{"BLMPOP", "2", 2, "list-a", "list-b", "LEFT"},
+			timeout:  time.Second * 2,
+			blocking: true,
+		},
+		{
+			args:     []interface{}{"XREAD", "COUNT", 2, "BLOCK", 1500, "STREAMS", "some-stream", "$"},
+			timeout:  time.Millisecond * 1500,
+			blocking: true,
+		},
+		{
+			args: []interface{}{"XREAD", "COUNT", 2, "STREAMS", "some-stream", "0"},
+		},
+		{
+			args:     []interface{}{"WAIT", 1, 100},
+			timeout:  time.Millisecond * 100,
+			blocking: true,
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(fmt.Sprint(ts.args), func(t *testing.T) {
+			timeout, blocking := blockingTimeout(ts.args)
+			assert.Equal(t, ts.timeout, timeout)
+			assert.Equal(t, ts.blocking, blocking)
+		})
+	}
+}
+
+func TestConnectWithOptions_Database(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	for _, protocol := range []int{2, 3} {
+		client, err := ConnectWithOptions(context.Background(), Options{
+			Address:  server.Addr(),
+			Database: protocol,
+			Protocol: protocol,
+		})
+		require.NoError(t, err)
+
+		_, err = client.Do(context.Background(), "SET", "some-key", "some-value")
+		require.NoError(t, err)
+		require.NoError(t, client.Close())
+
+		value, err := server.DB(protocol).Get("some-key")
+		require.NoError(t, err)
+		assert.Equal(t, "some-value", value)
+	}
+}
+
+func TestConnectWithOptions_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redis.sock")
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+
+	newFakeServerWithListener(t, listener, func(w io.Writer, args []string) {
+		io.WriteString(w, "+PONG\r\n")
+	})
+
+	options, err := ParseURL("unix://" + path)
+	require.NoError(t, err)
+
+	client, err := ConnectWithOptions(context.Background(), options)
+	require.NoError(t, err)
+	defer client.Close()
+
+	result, err := client.Do(context.Background(), "PING")
+	require.NoError(t, err)
+	assert.Equal(t, "PONG", result.Content())
+}
+
+func TestConnectWithOptions_ReadTimeout(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	client, err := ConnectWithOptions(context.Background(), Options{
+		Address:     server.Addr(),
+		ReadTimeout: time.Millisecond * 50,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	// blocking commands wait for the read timeout plus the time they block for
+	result, err := client.Do(context.Background(), "BLPOP", "some-list", 1)
+	require.NoError(t, err)
+	assert.Nil(t, result.Content())
+}
+
+func TestConnectWithOptions_MaxBulkLength(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	require.NoError(t, server.Set("small", "12345"))
+	require.NoError(t, server.Set("large", "0123456789"))
+
+	client, err := ConnectWithOptions(context.Background(), Options{
+		Address:       server.Addr(),
+		MaxBulkLength: 5,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	result, err := client.Do(context.Background(), "GET", "small")
+	require.NoError(t, err)
+	assert.Equal(t, "12345", result.Content())
+
+	_, err = client.Do(context.Background(), "GET", "large")
+	assert.EqualError(t, err, "bulk string of 10 bytes is bigger than the max length of 5 bytes")
+	assert.True(t, client.Broken())
+}
+
+func TestConnectWithOptions_TLS(t *testing.T) {
+	authority := newTestAuthority(t)
+	serverCertificate := authority.issue(t, "redis.test", x509.ExtKeyUsageServerAuth)
+	clientCertificate := authority.issue(t, "some-client", x509.ExtKeyUsageClientAuth)
+	otherCertificate := newTestAuthority(t).issue(t, "some-client", x509.ExtKeyUsageClientAuth)
+
+	tt := []struct {
+		name   string
+		mutual bool
+		config *tls.Config
+		err    string
+	}{
+		{
+			name: "with TLS",
+			config: &tls.Config{
+				RootCAs:    authority.pool,
+				ServerName: "redis.test",
+			},
+		},
+		{
+			name:   "with mutual TLS",
+			mutual: true,
+			config: &tls.Config{
+				RootCAs:      authority.pool,
+				ServerName:   "redis.test",
+				Certificates: []tls.Certificate{clientCertificate},
+			},
+		},
+		{
+			name: "with the wrong server name",
+			config: &tls.Config{
+				RootCAs:    authority.pool,
+				ServerName: "other.test",
+			},
+			err: "certificate is valid for redis.test, not other.test",
+		},
+		{
+			name: "without the server name it uses the address",
+			config: &tls.Config{
+				RootCAs: authority.pool,
+			},
+			err: "cannot validate certificate for 127.0.0.1",
+		},
+		{
+			name:   "with a client certificate from another authority",
+			mutual: true,
+			config: &tls.Config{
+				RootCAs:      authority.pool,
+				ServerName:   "redis.test",
+				Certificates: []tls.Certificate{otherCertificate},
+			},
+			err: "failed to initialize connection",
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			serverConfig := &tls.Config{
+				Certificates: []tls.Certificate{serverCertificate},
+			}
+
+			if ts.mutual {
+				serverConfig.ClientAuth = tls.RequireAndVerifyClientCert
+				serverConfig.ClientCAs = authority.pool
+			}
+
+			server, err := miniredis.RunTLS(serverConfig)
+			require.NoError(t, err)
+			defer server.Close()
+
+			// with TLS 1.3 the server only refuses the client certificate after the handshake,
+			// HELLO makes sure we talk to the server before returning.
+			client, err := ConnectWithOptions(context.Background(), Options{
+				Address:   server.Addr(),
+				Protocol:  3,
+				TLSConfig: ts.config,
+			})
+			if ts.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), ts.err)
+				return
+			}
+
+			require.NoError(t, err)
+			defer client.Close()
+
+			result, err := client.Do(context.Background(), "SET", "some-key", "some-value")
+			require.NoError(t, err)
+			assert.Equal(t, "OK", result.Content())
+		})
+	}
+}
+
+type testAuthority struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	pool        *x509.CertPool
+}
+
+func newTestAuthority(t *testing.T) *testAuthority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test authority"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+
+	return &testAuthority{
+		certificate: certificate,
+		key:         key,
+		pool:        pool,
+	}
+}
+
+func (a *testAuthority) issue(t *testing.T, name string, usage x509.ExtKeyUsage) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, a.certificate, &key.PublicKey, a.key)
+	require.NoError(t, err)
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}
+}
@@ -0,0 +1,563 @@
+package redis_client
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultMaxRedirects = 5
+)
+
+var (
+	_ io.Closer = &ClusterClient{}
+
+	// ErrClusterClosed is returned when using a closed ClusterClient.
+	ErrClusterClosed = errors.New("redis: cluster client is closed")
+)
+
+// ClusterOptions configures a ClusterClient.
+type ClusterOptions struct {
+	// Addresses are the seed nodes the cluster layout is loaded from, only one of them has to be up.
+	Addresses []string
+	// Options is how connections to the nodes are created, Address is replaced with the address of each node.
+	Options Options
+	// MaxRedirects is how many MOVED and ASK redirects a command follows before the error is returned,
+	// defaults to 5.
+	MaxRedirects int
+}
+
+// ClusterClient sends commands to the master that owns the slot of their keys on a redis cluster, keeping
+// a Pool of connections for each node. the layout of the cluster is loaded from the seed nodes with
+// `CLUSTER SHARDS`, or `CLUSTER SLOTS` on servers older than redis 7, and loaded again when a node answers
+// with MOVED, the pools for nodes that are not masters on the new layout are closed. ASK redirects for slots
+// being migrated are followed without changing the layout.
+//
+// commands with keys on different slots fail with ErrCrossSlot, commands without keys go to any master.
+type ClusterClient struct {
+	Commands
+	options ClusterOptions
+
+	mutex  sync.RWMutex
+	layout *clusterLayout
+	nodes  map[string]*Pool
+	closed bool
+
+	// refreshMutex makes commands that got MOVED at the same time load the layout only once
+	refreshMutex sync.Mutex
+}
+
+// slotRange is a range of slots, both inclusive, served by the master at address.
+type slotRange struct {
+	start   int
+	end     int
+	address string
+}
+
+// clusterLayout is which master serves each slot, it is replaced as a whole when the cluster changes.
+type clusterLayout struct {
+	ranges  []slotRange
+	masters []string
+}
+
+func newClusterLayout(ranges []slotRange) *clusterLayout {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start < ranges[j].start
+	})
+
+	layout := &clusterLayout{
+		ranges: ranges,
+	}
+
+	seen := map[string]bool{}
+	for _, r := range ranges {
+		if !seen[r.address] {
+			seen[r.address] = true
+			layout.masters = append(layout.masters, r.address)
+		}
+	}
+
+	return layout
+}
+
+// address returns the master serving the slot.
+func (l *clusterLayout) address(slot int) (string, bool) {
+	index := sort.Search(len(l.ranges), func(i int) bool {
+		return l.ranges[i].end >= slot
+	})
+
+	if index == len(l.ranges) || l.ranges[index].start > slot {
+		return "", false
+	}
+
+	return l.ranges[index].address, true
+}
+
+// NewClusterClient loads the cluster layout from the seed nodes and returns a client for the cluster.
+func NewClusterClient(ctx context.Context, options ClusterOptions) (*ClusterClient, error) {
+	if len(options.Addresses) == 0 {
+		return nil, errors.New("at least one seed address is required to connect to a cluster")
+	}
+
+	if options.MaxRedirects == 0 {
+		options.MaxRedirects = defaultMaxRedirects
+	}
+
+	c := &ClusterClient{
+		options: options,
+		nodes:   map[string]*Pool{},
+	}
+	c.Commands = Commands{do: c.Do}
+
+	if err := c.refresh(ctx, nil); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Do sends the command to the master that owns the slot of its keys and waits for its reply, following
+// MOVED and ASK redirects.
+func (c *ClusterClient) Do(ctx context.Context, args ...interface{}) (*Result, error) {
+	if len(args) == 0 {
+		return nil, errors.New("no command given")
+	}
+
+	slot, err := commandSlot(args)
+	if err != nil {
+		return nil, err
+	}
+
+	address, err := c.address(slot)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.execute(ctx, address, false, args)
+}
+
+// Close closes the connections to all nodes.
+func (c *ClusterClient) Close() error {
+	c.mutex.Lock()
+	nodes := c.nodes
+	c.nodes = map[string]*Pool{}
+	c.closed = true
+	c.mutex.Unlock()
+
+	for _, pool := range nodes {
+		pool.Close()
+	}
+
+	return nil
+}
+
+// execute sends the command to the node at address, following redirects to other nodes. asking sends
+// ASKING before the command, for commands that got an ASK redirect to this node.
+func (c *ClusterClient) execute(ctx context.Context, address string, asking bool, args []interface{}) (*Result, error) {
+	for redirects := 0; ; redirects++ {
+		layout := c.currentLayout()
+
+		pool, err := c.node(address)
+		if err != nil {
+			return nil, err
+		}
+
+		var result *Result
+		if asking {
+			// ASKING only applies to the next command, so both must go on the same connection
+			var results []*Result
+			results, err = pool.pipeline(ctx, [][]interface{}{{"ASKING"}, args})
+			if err == nil {
+				result = results[1]
+			}
+		} else {
+			result, err = pool.Do(ctx, args...)
+		}
+
+		if errors.Is(err, ErrPoolClosed) && redirects < c.options.MaxRedirects {
+			// the node left the layout while the command was on its way, it is sent again on a new pool and
+			// the node redirects it if it doesn't serve the slot anymore
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		redirect := result.Err()
+		if redirects >= c.options.MaxRedirects || !(IsMoved(redirect) || IsAsk(redirect)) {
+			return result, nil
+		}
+
+		target, err := redirectAddress(redirect, address)
+		if err != nil {
+			return nil, err
+		}
+
+		asking = IsAsk(redirect)
+		if !asking {
+			// the command follows the redirect even if the layout can't be loaded right now
+			c.refresh(ctx, layout)
+		}
+
+		address = target
+	}
+}
+
+// redirectAddress reads the node address from MOVED and ASK errors, like `MOVED 3999 127.0.0.1:6381`.
+// redis 7 sends addresses without a host when the host is the same as the node that sent the error.
+func redirectAddress(redirect error, from string) (string, error) {
+	var redisErr *RedisError
+	if !errors.As(redirect, &redisErr) {
+		return "", errors.Wrap(redirect, "invalid redirect")
+	}
+
+	fields := strings.Fields(redisErr.Message)
+	if len(fields) != 2 {
+		return "", fmt.Errorf("invalid redirect: %v", redirect)
+	}
+
+	address := fields[1]
+	if strings.HasPrefix(address, ":") {
+		host, _, err := net.SplitHostPort(from)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid redirect: %v", redirect)
+		}
+
+		address = net.JoinHostPort(host, address[1:])
+	}
+
+	return address, nil
+}
+
+func (c *ClusterClient) currentLayout() *clusterLayout {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.layout
+}
+
+// address returns the master that owns the slot, or any master for commands without keys (slot -1).
+func (c *ClusterClient) address(slot int) (string, error) {
+	layout := c.currentLayout()
+	if layout == nil || len(layout.masters) == 0 {
+		return "", errors.New("cluster layout has no masters")
+	}
+
+	if slot < 0 {
+		return layout.masters[rand.Intn(len(layout.masters))], nil
+	}
+
+	address, ok := layout.address(slot)
+	if !ok {
+		return "", fmt.Errorf("no cluster node serves slot %v", slot)
+	}
+
+	return address, nil
+}
+
+// node returns the pool for the node at address, creating it if it doesn't exist yet.
+func (c *ClusterClient) node(address string) (*Pool, error) {
+	c.mutex.RLock()
+	pool, ok := c.nodes[address]
+	c.mutex.RUnlock()
+
+	if ok {
+		return pool, nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return nil, ErrClusterClosed
+	}
+
+	if pool, ok := c.nodes[address]; ok {
+		return pool, nil
+	}
+
+	pool = NewPool(PoolOptions{
+		Options: c.nodeOptions(address),
+	})
+	c.nodes[address] = pool
+
+	return pool, nil
+}
+
+func (c *ClusterClient) nodeOptions(address string) Options {
+	return c.options.Options.withAddress(address)
+}
+
+// refresh loads the cluster layout again, unless it was replaced since stale was read.
+func (c *ClusterClient) refresh(ctx context.Context, stale *clusterLayout) error {
+	c.refreshMutex.Lock()
+	defer c.refreshMutex.Unlock()
+
+	current := c.currentLayout()
+	if current != stale {
+		return nil
+	}
+
+	// the masters we know about are tried first, the seeds might not even be part of the cluster anymore
+	var addresses []string
+	if current != nil {
+		addresses = append(addresses, current.masters...)
+	}
+	addresses = append(addresses, c.options.Addresses...)
+
+	var lastErr error
+	for _, address := range addresses {
+		layout, err := c.loadLayout(ctx, address)
+		if err == nil {
+			c.replaceLayout(layout)
+			return nil
+		}
+
+		lastErr = err
+	}
+
+	return errors.Wrap(lastErr, "failed to load the cluster layout")
+}
+
+// replaceLayout makes commands use the new layout and closes the pools for nodes that are not masters on it,
+// connections in use are closed once they are returned to the old pools.
+func (c *ClusterClient) replaceLayout(layout *clusterLayout) {
+	masters := make(map[string]bool, len(layout.masters))
+	for _, address := range layout.masters {
+		masters[address] = true
+	}
+
+	var removed []*Pool
+
+	c.mutex.Lock()
+	c.layout = layout
+	for address, pool := range c.nodes {
+		if !masters[address] {
+			removed = append(removed, pool)
+			delete(c.nodes, address)
+		}
+	}
+	c.mutex.Unlock()
+
+	for _, pool := range removed {
+		pool.Close()
+	}
+}
+
+// loadLayout asks the node for the cluster layout with `CLUSTER SHARDS`, falling back to `CLUSTER SLOTS`.
+func (c *ClusterClient) loadLayout(ctx context.Context, address string) (*clusterLayout, error) {
+	pool, err := c.node(address)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := pool.Do(ctx, "CLUSTER", "SHARDS")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load the cluster layout from %v", address)
+	}
+
+	var ranges []slotRange
+	if result.Err() == nil {
+		ranges, err = parseClusterShards(result, address, c.options.Options.TLSConfig != nil)
+	} else {
+		result, err = pool.Do(ctx, "CLUSTER", "SLOTS")
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load the cluster layout from %v", address)
+		}
+
+		if err := result.Err(); err != nil {
+			return nil, errors.Wrapf(err, "CLUSTER SLOTS failed on %v", address)
+		}
+
+		ranges, err = parseClusterSlots(result, address)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid cluster layout from %v", address)
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("cluster layout from %v has no slots", address)
+	}
+
+	return newClusterLayout(ranges), nil
+}
+
+// parseClusterSlots reads the `CLUSTER SLOTS` reply, an array of slot ranges with the start, the end and
+// the nodes serving them, the master first. an empty host means the same host as the node that replied.
+func parseClusterSlots(result *Result, from string) ([]slotRange, error) {
+	items, err := result.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := make([]slotRange, 0, len(items))
+	for _, item := range items {
+		if item.Len() < 3 {
+			return nil, fmt.Errorf("slot range should have at least 3 elements: %#v", item.content)
+		}
+
+		start, err := item.Index(0).Int64()
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid slot range start")
+		}
+
+		end, err := item.Index(1).Int64()
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid slot range end")
+		}
+
+		master := item.Index(2)
+		if master.Len() < 2 {
+			return nil, fmt.Errorf("node should have a host and a port: %#v", master.content)
+		}
+
+		host, err := stringValue(master.Index(0).content)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid node host")
+		}
+
+		port, err := master.Index(1).Int64()
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid node port")
+		}
+
+		ranges = append(ranges, slotRange{
+			start:   int(start),
+			end:     int(end),
+			address: nodeAddress(host, port, from),
+		})
+	}
+
+	return ranges, nil
+}
+
+// parseClusterShards reads the `CLUSTER SHARDS` reply, a list of shards with their slot ranges and nodes as
+// maps on RESP3 or flat arrays of keys and values on RESP2.
+func parseClusterShards(result *Result, from string, useTLS bool) ([]slotRange, error) {
+	shards, err := result.Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	var ranges []slotRange
+	for _, shard := range shards {
+		fields, err := pairs(shard)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid shard")
+		}
+
+		var (
+			slots   []interface{}
+			address string
+		)
+
+		for _, field := range fields {
+			switch field.Key {
+			case "slots":
+				slots, _ = field.Value.([]interface{})
+			case "nodes":
+				nodes, _ := field.Value.([]interface{})
+				for _, node := range nodes {
+					if address, err = shardMaster(node, from, useTLS); err != nil {
+						return nil, err
+					}
+
+					if address != "" {
+						break
+					}
+				}
+			}
+		}
+
+		if len(slots) == 0 {
+			continue
+		}
+
+		if address == "" {
+			return nil, fmt.Errorf("shard has slots but no master: %#v", shard)
+		}
+
+		if len(slots)%2 != 0 {
+			return nil, fmt.Errorf("shard slots should be start and end pairs: %#v", slots)
+		}
+
+		for x := 0; x < len(slots); x += 2 {
+			start, startOK := slots[x].(int64)
+			end, endOK := slots[x+1].(int64)
+			if !startOK || !endOK {
+				return nil, fmt.Errorf("shard slots should be integers: %#v", slots)
+			}
+
+			ranges = append(ranges, slotRange{
+				start:   int(start),
+				end:     int(end),
+				address: address,
+			})
+		}
+	}
+
+	return ranges, nil
+}
+
+// shardMaster returns the address of the node if it is a master, or an empty string if it isn't.
+func shardMaster(node interface{}, from string, useTLS bool) (string, error) {
+	fields, err := pairs(node)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid shard node")
+	}
+
+	var (
+		role, ip, endpoint string
+		port, tlsPort      int64
+	)
+
+	for _, field := range fields {
+		switch field.Key {
+		case "role":
+			role, _ = field.Value.(string)
+		case "ip":
+			ip, _ = field.Value.(string)
+		case "endpoint":
+			endpoint, _ = field.Value.(string)
+		case "port":
+			port, _ = field.Value.(int64)
+		case "tls-port":
+			tlsPort, _ = field.Value.(int64)
+		}
+	}
+
+	if role != "master" {
+		return "", nil
+	}
+
+	host := endpoint
+	if host == "" || host == "?" {
+		host = ip
+	}
+
+	if useTLS && tlsPort != 0 {
+		port = tlsPort
+	}
+
+	return nodeAddress(host, port, from), nil
+}
+
+// nodeAddress joins the host and port of a node, an empty host is the same host as the node at from.
+func nodeAddress(host string, port int64, from string) string {
+	if host == "" {
+		host, _, _ = net.SplitHostPort(from)
+	}
+
+	return net.JoinHostPort(host, strconv.FormatInt(port, 10))
+}
@@ -0,0 +1,422 @@
+package redis_client
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"sync"
+)
+
+// firstError keeps the first error reported by many goroutines.
+type firstError struct {
+	mutex sync.Mutex
+	err   error
+}
+
+func (e *firstError) set(err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+func (e *firstError) get() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.err
+}
+
+// Pipeline creates a pipeline that sends each command to the master that owns the slot of its keys. commands
+// for the same node go in a single round trip, the nodes are called in parallel and the results are returned
+// in the order the commands were queued. MOVED and ASK replies are followed for each command on its own.
+//
+// commands with keys on different slots get a Result with ErrCrossSlot, the others are still executed.
+func (c *ClusterClient) Pipeline() *Pipeline {
+	return &Pipeline{
+		exec: c.pipeline,
+	}
+}
+
+func (c *ClusterClient) pipeline(ctx context.Context, commands [][]interface{}) ([]*Result, error) {
+	layout := c.currentLayout()
+	results := make([]*Result, len(commands))
+	addresses := make([]string, len(commands))
+
+	// indexes of the commands for each node, in the order they were queued
+	byNode := map[string][]int{}
+	for x, args := range commands {
+		slot, err := commandSlot(args)
+		if err == nil {
+			addresses[x], err = c.address(slot)
+		}
+
+		if err != nil {
+			results[x] = &Result{content: err}
+			continue
+		}
+
+		byNode[addresses[x]] = append(byNode[addresses[x]], x)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		failures firstError
+	)
+
+	for address, indexes := range byNode {
+		wg.Add(1)
+		go func(address string, indexes []int) {
+			defer wg.Done()
+
+			nodeCommands := make([][]interface{}, 0, len(indexes))
+			for _, x := range indexes {
+				nodeCommands = append(nodeCommands, commands[x])
+			}
+
+			var nodeResults []*Result
+			pool, err := c.node(address)
+			if err == nil {
+				nodeResults, err = pool.pipeline(ctx, nodeCommands)
+			} else {
+				nodeResults = failRemaining(nil, nodeCommands, err)
+			}
+
+			if err != nil {
+				failures.set(err)
+			}
+
+			for y, x := range indexes {
+				results[x] = nodeResults[y]
+			}
+		}(address, indexes)
+	}
+
+	wg.Wait()
+
+	for x, result := range results {
+		redirect := result.Err()
+		if !IsMoved(redirect) && !IsAsk(redirect) {
+			continue
+		}
+
+		target, err := redirectAddress(redirect, addresses[x])
+		if err != nil {
+			results[x] = &Result{content: err}
+			continue
+		}
+
+		if IsMoved(redirect) {
+			// only the first command that got MOVED loads the layout again, the others see it has changed
+			c.refresh(ctx, layout)
+		}
+
+		wg.Add(1)
+		go func(x int, target string, asking bool) {
+			defer wg.Done()
+
+			result, err := c.execute(ctx, target, asking, commands[x])
+			if err != nil {
+				failures.set(err)
+				result = &Result{content: err}
+			}
+
+			results[x] = result
+		}(x, target, IsAsk(redirect))
+	}
+
+	wg.Wait()
+
+	return results, failures.get()
+}
+
+// slotKeys are the keys given to a multi-key command that are on the same slot and where they were given.
+type slotKeys struct {
+	keys    []string
+	indexes []int
+}
+
+// groupBySlot splits the keys by slot, keeping the order they were given in.
+func groupBySlot(keys []string) []*slotKeys {
+	var groups []*slotKeys
+	bySlot := map[int]*slotKeys{}
+
+	for x, key := range keys {
+		slot := KeySlot(key)
+
+		group, ok := bySlot[slot]
+		if !ok {
+			group = &slotKeys{}
+			bySlot[slot] = group
+			groups = append(groups, group)
+		}
+
+		group.keys = append(group.keys, key)
+		group.indexes = append(group.indexes, x)
+	}
+
+	return groups
+}
+
+// MGet returns the values for the keys like Commands.MGet, but keys don't have to be on the same slot.
+// there is a MGET for each slot and they are sent to the nodes in parallel.
+func (c *ClusterClient) MGet(ctx context.Context, keys ...string) ([]NullString, error) {
+	groups := groupBySlot(keys)
+
+	pipeline := c.Pipeline()
+	for _, group := range groups {
+		pipeline.Queue("MGET", group.keys)
+	}
+
+	results, err := pipeline.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]NullString, len(keys))
+	for x, group := range groups {
+		slotValues, err := nullStringSlice(results[x])
+		if err != nil {
+			return nil, err
+		}
+
+		if len(slotValues) != len(group.keys) {
+			return nil, fmt.Errorf("MGET returned %v values for %v keys", len(slotValues), len(group.keys))
+		}
+
+		for y, index := range group.indexes {
+			values[index] = slotValues[y]
+		}
+	}
+
+	return values, nil
+}
+
+// MSet sets many keys like Commands.MSet, but keys don't have to be on the same slot. there is a MSET for
+// each slot and they are sent to the nodes in parallel, so unlike a single MSET the keys are not all set
+// atomically, if an error is returned some of the keys might have been set.
+func (c *ClusterClient) MSet(ctx context.Context, keysAndValues ...interface{}) error {
+	flattened, err := flattenArgs(nil, keysAndValues)
+	if err != nil {
+		return err
+	}
+
+	if len(flattened) == 0 || len(flattened)%2 != 0 {
+		return fmt.Errorf("MSET needs key and value pairs but got %v arguments", len(flattened))
+	}
+
+	keys := make([]string, 0, len(flattened)/2)
+	for x := 0; x < len(flattened); x += 2 {
+		keys = append(keys, string(flattened[x]))
+	}
+
+	pipeline := c.Pipeline()
+	for _, group := range groupBySlot(keys) {
+		args := make([]interface{}, 0, len(group.keys)*2+1)
+		args = append(args, "MSET")
+		for _, index := range group.indexes {
+			args = append(args, flattened[index*2], flattened[index*2+1])
+		}
+
+		pipeline.Queue(args...)
+	}
+
+	results, err := pipeline.Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if err := result.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Del removes the keys like Commands.Del, but keys don't have to be on the same slot. there is a DEL for each
+// slot and they are sent to the nodes in parallel, the counts of removed keys are added up.
+func (c *ClusterClient) Del(ctx context.Context, keys ...string) (int64, error) {
+	pipeline := c.Pipeline()
+	for _, group := range groupBySlot(keys) {
+		pipeline.Queue("DEL", group.keys)
+	}
+
+	results, err := pipeline.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	for _, result := range results {
+		count, err := result.Int64()
+		if err != nil {
+			return 0, err
+		}
+
+		removed += count
+	}
+
+	return removed, nil
+}
+
+// forEachMaster calls fn for every master in parallel and returns the first error, wrapped with the
+// address of the master that failed.
+func (c *ClusterClient) forEachMaster(ctx context.Context, fn func(ctx context.Context, pool *Pool) error) error {
+	layout := c.currentLayout()
+	if layout == nil || len(layout.masters) == 0 {
+		return errors.New("cluster layout has no masters")
+	}
+
+	var (
+		wg       sync.WaitGroup
+		failures firstError
+	)
+
+	for _, address := range layout.masters {
+		wg.Add(1)
+		go func(address string) {
+			defer wg.Done()
+
+			pool, err := c.node(address)
+			if err == nil {
+				err = fn(ctx, pool)
+			}
+
+			if err != nil {
+				failures.set(errors.Wrapf(err, "failed on %v", address))
+			}
+		}(address)
+	}
+
+	wg.Wait()
+
+	return failures.get()
+}
+
+// FlushAll removes all keys from every master.
+func (c *ClusterClient) FlushAll(ctx context.Context) error {
+	return c.forEachMaster(ctx, func(ctx context.Context, pool *Pool) error {
+		return pool.status(ctx, "FLUSHALL")
+	})
+}
+
+// DBSize returns how many keys there are on the cluster, adding up the number of keys on every master.
+func (c *ClusterClient) DBSize(ctx context.Context) (int64, error) {
+	var (
+		mutex sync.Mutex
+		total int64
+	)
+
+	err := c.forEachMaster(ctx, func(ctx context.Context, pool *Pool) error {
+		size, err := pool.int64(ctx, "DBSIZE")
+		if err != nil {
+			return err
+		}
+
+		mutex.Lock()
+		total += size
+		mutex.Unlock()
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// Keys returns the keys matching pattern from every master, in no particular order. like KEYS on a single
+// node it blocks the masters while they go through all their keys, Scan is the better option outside of tests.
+func (c *ClusterClient) Keys(ctx context.Context, pattern string) ([]string, error) {
+	var (
+		mutex sync.Mutex
+		keys  []string
+	)
+
+	err := c.forEachMaster(ctx, func(ctx context.Context, pool *Pool) error {
+		nodeKeys, err := pool.strings(ctx, "KEYS", pattern)
+		if err != nil {
+			return err
+		}
+
+		mutex.Lock()
+		keys = append(keys, nodeKeys...)
+		mutex.Unlock()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Scan goes through the keys matching pattern on every master with SCAN until the cursor of every master gets
+// back to 0 and returns all keys found, in no particular order. count is the COUNT hint for each SCAN call,
+// 0 leaves it to the server. keys added or removed while the scan runs might or might not be returned.
+func (c *ClusterClient) Scan(ctx context.Context, pattern string, count int64) ([]string, error) {
+	var (
+		mutex sync.Mutex
+		keys  []string
+	)
+
+	err := c.forEachMaster(ctx, func(ctx context.Context, pool *Pool) error {
+		cursor := "0"
+		for {
+			args := []interface{}{"SCAN", cursor, "MATCH", pattern}
+			if count > 0 {
+				args = append(args, "COUNT", count)
+			}
+
+			result, err := pool.result(ctx, args...)
+			if err != nil {
+				return err
+			}
+
+			next, nodeKeys, err := scanReply(result)
+			if err != nil {
+				return err
+			}
+
+			mutex.Lock()
+			keys = append(keys, nodeKeys...)
+			mutex.Unlock()
+
+			if next == "0" {
+				return nil
+			}
+
+			cursor = next
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// scanReply reads the next cursor and the keys from a SCAN reply.
+func scanReply(result *Result) (string, []string, error) {
+	if result.Len() != 2 {
+		return "", nil, fmt.Errorf("SCAN reply should have a cursor and keys but was %v", result.Content())
+	}
+
+	cursor, _, err := result.Index(0).String()
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to read SCAN cursor")
+	}
+
+	keys, err := result.Index(1).StringSlice()
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to read SCAN keys")
+	}
+
+	return cursor, keys, nil
+}
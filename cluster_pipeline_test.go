@@ -0,0 +1,217 @@
+package redis_client
+
+import (
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+)
+
+// newClusterSeed is a seed node that splits the slots between first and second, keys a and d are on the second
+// server and keys b and c on the first one.
+func newClusterSeed(t *testing.T, first *miniredis.Miniredis, second *miniredis.Miniredis) *fakeServer {
+	return newFakeServer(t, func(w io.Writer, args []string) {
+		writeClusterShards(w,
+			testShard{start: 0, end: 8191, address: first.Addr()},
+			testShard{start: 8192, end: 16383, address: second.Addr()},
+		)
+	})
+}
+
+func TestClusterClient_Pipeline(t *testing.T) {
+	first, err := miniredis.Run()
+	require.NoError(t, err)
+	defer first.Close()
+
+	second, err := miniredis.Run()
+	require.NoError(t, err)
+	defer second.Close()
+
+	seed := newClusterSeed(t, first, second)
+
+	client, err := NewClusterClient(context.Background(), ClusterOptions{Addresses: []string{seed.Addr()}})
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx := context.Background()
+
+	pipeline := client.Pipeline()
+	pipeline.Queue("SET", "a", "on second")
+	pipeline.Queue("SET", "b", "on first")
+	pipeline.Queue("GET", "a")
+	pipeline.Queue("MGET", "a", "b")
+	pipeline.Queue("GET", "b")
+	pipeline.Queue("INCR", "b")
+	pipeline.Queue("PING")
+
+	results, err := pipeline.Exec(ctx)
+	require.NoError(t, err)
+	require.Len(t, results, 7)
+
+	for _, x := range []int{0, 1} {
+		require.NoError(t, results[x].Err())
+	}
+
+	assert.Equal(t, "on second", results[2].Content())
+	assert.True(t, errors.Is(results[3].Err(), ErrCrossSlot))
+	assert.Equal(t, "on first", results[4].Content())
+	assert.EqualError(t, results[5].Err(), "ERR value is not an integer or out of range")
+	assert.Equal(t, "PONG", results[6].Content())
+
+	second.CheckGet(t, "a", "on second")
+	first.CheckGet(t, "b", "on first")
+	assert.False(t, first.Exists("a"))
+	assert.False(t, second.Exists("b"))
+}
+
+func TestClusterClient_PipelineRedirects(t *testing.T) {
+	target, err := miniredis.Run()
+	require.NoError(t, err)
+	defer target.Close()
+
+	require.NoError(t, target.Set("moved-key", "from moved"))
+	require.NoError(t, target.Set("asked-key", "from ask"))
+
+	// the seed keeps all slots but says two of the keys are on target
+	var seed *fakeServer
+	seed = newFakeServer(t, func(w io.Writer, args []string) {
+		switch {
+		case args[0] == "CLUSTER":
+			writeClusterShards(w, testShard{start: 0, end: 16383, address: seed.Addr()})
+		case args[1] == "moved-key":
+			io.WriteString(w, "-MOVED "+strconv.Itoa(KeySlot(args[1]))+" "+target.Addr()+"\r\n")
+		case args[1] == "asked-key":
+			_, port, _ := net.SplitHostPort(target.Addr())
+			io.WriteString(w, "-ASK "+strconv.Itoa(KeySlot(args[1]))+" :"+port+"\r\n")
+		default:
+			io.WriteString(w, "$10\r\nfrom local\r\n")
+		}
+	})
+
+	client, err := NewClusterClient(context.Background(), ClusterOptions{Addresses: []string{seed.Addr()}})
+	require.NoError(t, err)
+	defer client.Close()
+
+	pipeline := client.Pipeline()
+	pipeline.Queue("GET", "moved-key")
+	pipeline.Queue("GET", "local-key")
+	pipeline.Queue("GET", "asked-key")
+
+	results, err := pipeline.Exec(context.Background())
+	require.NoError(t, err)
+
+	var values []interface{}
+	for _, result := range results {
+		values = append(values, result.Content())
+	}
+
+	assert.Equal(t, []interface{}{"from moved", "from local", "from ask"}, values)
+	assert.Equal(t, [][]string{
+		{"GET", "moved-key"},
+		{"GET", "local-key"},
+		{"GET", "asked-key"},
+	}, seed.Commands()[1:4])
+	assert.Equal(t, 2, countCommands(seed, "CLUSTER"))
+}
+
+func TestClusterClient_MultiKey(t *testing.T) {
+	first, err := miniredis.Run()
+	require.NoError(t, err)
+	defer first.Close()
+
+	second, err := miniredis.Run()
+	require.NoError(t, err)
+	defer second.Close()
+
+	seed := newClusterSeed(t, first, second)
+
+	client, err := NewClusterClient(context.Background(), ClusterOptions{Addresses: []string{seed.Addr()}})
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx := context.Background()
+
+	require.NoError(t, client.MSet(ctx, "a", 1, "b", 2, map[string]string{"c": "3", "d": "4"}))
+
+	second.CheckGet(t, "a", "1")
+	first.CheckGet(t, "b", "2")
+	first.CheckGet(t, "c", "3")
+	second.CheckGet(t, "d", "4")
+
+	values, err := client.MGet(ctx, "d", "b", "missing", "a", "c")
+	require.NoError(t, err)
+	assert.Equal(t, []NullString{
+		{String: "4", Valid: true},
+		{String: "2", Valid: true},
+		{},
+		{String: "1", Valid: true},
+		{String: "3", Valid: true},
+	}, values)
+
+	removed, err := client.Del(ctx, "a", "b", "c", "missing")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), removed)
+	assert.Equal(t, []string{"d"}, second.Keys())
+	assert.Empty(t, first.Keys())
+
+	assert.EqualError(t, client.MSet(ctx, "a", 1, "b"), "MSET needs key and value pairs but got 3 arguments")
+}
+
+func TestClusterClient_ScatterGather(t *testing.T) {
+	first, err := miniredis.Run()
+	require.NoError(t, err)
+	defer first.Close()
+
+	second, err := miniredis.Run()
+	require.NoError(t, err)
+	defer second.Close()
+
+	seed := newClusterSeed(t, first, second)
+
+	client, err := NewClusterClient(context.Background(), ClusterOptions{Addresses: []string{seed.Addr()}})
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx := context.Background()
+
+	keys := []string{"a", "b", "c", "d", "user:1", "user:2", "user:3"}
+	for _, key := range keys {
+		_, err := client.Set(ctx, key, "value", SetOptions{})
+		require.NoError(t, err)
+	}
+
+	require.NotEmpty(t, first.Keys())
+	require.NotEmpty(t, second.Keys())
+
+	size, err := client.DBSize(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(keys)), size)
+
+	found, err := client.Keys(ctx, "user:*")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"user:1", "user:2", "user:3"}, found)
+
+	found, err = client.Scan(ctx, "*", 2)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, keys, found)
+
+	found, err = client.Scan(ctx, "user:*", 0)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"user:1", "user:2", "user:3"}, found)
+
+	require.NoError(t, client.FlushAll(ctx))
+	assert.Empty(t, first.Keys())
+	assert.Empty(t, second.Keys())
+
+	address := first.Addr()
+	first.Close()
+
+	_, err = client.DBSize(ctx)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed on "+address)
+}
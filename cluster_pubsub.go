@@ -0,0 +1,317 @@
+package redis_client
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"io"
+	"sync"
+)
+
+var (
+	_ io.Closer = &ShardedPubSub{}
+)
+
+// ShardedPubSub receives messages from sharded channels on a cluster. every channel is subscribed on the
+// master that owns its slot, with a PubSub of its own for each of these masters, and the messages from all
+// of them are delivered on a single Messages channel.
+//
+// when the slot of a channel moves to another node the server unsubscribes the channel, or refuses it
+// with MOVED after a reconnect, and the channel is subscribed again on the node that owns the slot now.
+type ShardedPubSub struct {
+	cluster  *ClusterClient
+	options  PubSubOptions
+	messages chan Message
+	ctx      context.Context
+	cancel   context.CancelFunc
+	loops    sync.WaitGroup
+
+	mutex sync.Mutex
+	nodes map[string]*PubSub
+	// channels are the subscribed channels and the address of the node they are on, an empty address
+	// means the channel is waiting to be subscribed
+	channels map[string]string
+	closed   bool
+}
+
+// ShardedPubSub creates a ShardedPubSub for this cluster, connections are only opened when channels are
+// subscribed. options.Options is not used, connections to the nodes are made with the cluster options.
+func (c *ClusterClient) ShardedPubSub(options PubSubOptions) *ShardedPubSub {
+	if options.ChannelSize == 0 {
+		options.ChannelSize = defaultMessageChannelSize
+	}
+
+	s := &ShardedPubSub{
+		cluster:  c,
+		options:  options,
+		messages: make(chan Message, options.ChannelSize),
+		nodes:    map[string]*PubSub{},
+		channels: map[string]string{},
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	return s
+}
+
+// Messages returns the channel messages are delivered on, it is closed once the ShardedPubSub is closed.
+func (s *ShardedPubSub) Messages() <-chan Message {
+	return s.messages
+}
+
+// SSubscribe subscribes to the sharded channels on the masters that own their slots and waits for all of
+// them to confirm, the channels don't have to be on the same slot.
+func (s *ShardedPubSub) SSubscribe(ctx context.Context, channels ...string) error {
+	if len(channels) == 0 {
+		return errors.New("SSUBSCRIBE needs at least one channel")
+	}
+
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return ErrPubSubClosed
+	}
+
+	for _, channel := range channels {
+		if _, ok := s.channels[channel]; !ok {
+			s.channels[channel] = ""
+		}
+	}
+	s.mutex.Unlock()
+
+	return s.subscribe(ctx, channels)
+}
+
+// SUnsubscribe unsubscribes from the sharded channels, or from all of them if none are given.
+func (s *ShardedPubSub) SUnsubscribe(channels ...string) error {
+	type unsubscribe struct {
+		node     *PubSub
+		channels []string
+	}
+
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return ErrPubSubClosed
+	}
+
+	var calls []unsubscribe
+	if len(channels) == 0 {
+		s.channels = map[string]string{}
+
+		for _, node := range s.nodes {
+			calls = append(calls, unsubscribe{node: node})
+		}
+	} else {
+		// SUNSUBSCRIBE takes channels from a single slot, like SSUBSCRIBE
+		bySlot := map[string]map[int][]string{}
+		for _, channel := range channels {
+			address := s.channels[channel]
+			delete(s.channels, channel)
+
+			if _, ok := s.nodes[address]; !ok {
+				continue
+			}
+
+			if bySlot[address] == nil {
+				bySlot[address] = map[int][]string{}
+			}
+
+			slot := KeySlot(channel)
+			bySlot[address][slot] = append(bySlot[address][slot], channel)
+		}
+
+		for address, slots := range bySlot {
+			for _, names := range slots {
+				calls = append(calls, unsubscribe{node: s.nodes[address], channels: names})
+			}
+		}
+	}
+	s.mutex.Unlock()
+
+	// the nodes call back into this ShardedPubSub, so they are not called with the mutex held
+	for _, call := range calls {
+		if err := call.node.SUnsubscribe(call.channels...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close closes the connections to all nodes and closes the Messages channel.
+func (s *ShardedPubSub) Close() error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return nil
+	}
+
+	s.closed = true
+	nodes := s.nodes
+	s.nodes = map[string]*PubSub{}
+	s.mutex.Unlock()
+
+	s.cancel()
+
+	for _, node := range nodes {
+		node.Close()
+	}
+
+	s.loops.Wait()
+	close(s.messages)
+
+	return nil
+}
+
+// subscribe groups the channels by slot, a single SSUBSCRIBE can't have channels from different slots.
+func (s *ShardedPubSub) subscribe(ctx context.Context, channels []string) error {
+	bySlot := map[int][]string{}
+	for _, channel := range channels {
+		slot := KeySlot(channel)
+		bySlot[slot] = append(bySlot[slot], channel)
+	}
+
+	for slot, names := range bySlot {
+		if err := s.subscribeSlot(ctx, slot, names); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// subscribeSlot subscribes to the channels on the node that owns the slot, loading the cluster layout again
+// if the node answers with MOVED.
+func (s *ShardedPubSub) subscribeSlot(ctx context.Context, slot int, channels []string) error {
+	for redirects := 0; ; redirects++ {
+		layout := s.cluster.currentLayout()
+
+		address, err := s.cluster.address(slot)
+		if err != nil {
+			return err
+		}
+
+		node, err := s.node(ctx, address)
+		if err != nil {
+			return err
+		}
+
+		err = node.SSubscribe(ctx, channels...)
+		if err == nil {
+			var unsubscribed []string
+
+			s.mutex.Lock()
+			for _, channel := range channels {
+				if _, ok := s.channels[channel]; ok {
+					s.channels[channel] = address
+				} else {
+					unsubscribed = append(unsubscribed, channel)
+				}
+			}
+			s.mutex.Unlock()
+
+			// SUnsubscribe was called while these were being subscribed and didn't know where they would be
+			if len(unsubscribed) > 0 {
+				return node.SUnsubscribe(unsubscribed...)
+			}
+
+			return nil
+		}
+
+		if !IsMoved(err) || redirects >= s.cluster.options.MaxRedirects {
+			return err
+		}
+
+		s.cluster.refresh(ctx, layout)
+	}
+}
+
+// node returns the PubSub for the node at address, connecting to it if there isn't one yet.
+func (s *ShardedPubSub) node(ctx context.Context, address string) (*PubSub, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil, ErrPubSubClosed
+	}
+
+	if node, ok := s.nodes[address]; ok {
+		return node, nil
+	}
+
+	node, err := newPubSub(ctx, PubSubOptions{
+		Options:      s.cluster.nodeOptions(address),
+		PingInterval: s.options.PingInterval,
+		ChannelSize:  s.options.ChannelSize,
+	}, s.dropped)
+	if err != nil {
+		return nil, err
+	}
+
+	s.nodes[address] = node
+
+	s.loops.Add(1)
+	go s.forward(node)
+
+	return node, nil
+}
+
+// forward sends the messages from a node to the Messages channel until the node is closed.
+func (s *ShardedPubSub) forward(node *PubSub) {
+	defer s.loops.Done()
+
+	for message := range node.Messages() {
+		select {
+		case s.messages <- message:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// dropped is called by the node PubSubs when the server unsubscribed channels by itself, they are subscribed
+// again in the background on the node that owns their slot now.
+func (s *ShardedPubSub) dropped(channels []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	for _, channel := range channels {
+		if _, ok := s.channels[channel]; ok {
+			s.channels[channel] = ""
+		}
+	}
+
+	s.loops.Add(1)
+	go s.resubscribe(channels)
+}
+
+// resubscribe loads the cluster layout again and subscribes to the channels, waiting longer after each failure.
+func (s *ShardedPubSub) resubscribe(channels []string) {
+	defer s.loops.Done()
+
+	retryWithBackoff(s.ctx, func() bool {
+		// channels unsubscribed in the meantime are not subscribed again
+		s.mutex.Lock()
+		pending := make([]string, 0, len(channels))
+		for _, channel := range channels {
+			if _, ok := s.channels[channel]; ok {
+				pending = append(pending, channel)
+			}
+		}
+		s.mutex.Unlock()
+
+		if len(pending) == 0 {
+			return true
+		}
+
+		s.cluster.refresh(s.ctx, s.cluster.currentLayout())
+
+		ctx, cancel := context.WithTimeout(s.ctx, s.cluster.options.Options.writeTimeout()+s.cluster.options.Options.readTimeout())
+		defer cancel()
+
+		return s.subscribe(ctx, pending) == nil
+	})
+}
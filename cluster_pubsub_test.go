@@ -0,0 +1,176 @@
+package redis_client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func writePush(w io.Writer, values ...string) {
+	io.WriteString(w, ">"+strconv.Itoa(len(values))+"\r\n")
+	for _, value := range values {
+		io.WriteString(w, "$"+strconv.Itoa(len(value))+"\r\n"+value+"\r\n")
+	}
+}
+
+// newShardNode is a fake cluster node that confirms sharded subscriptions, sends a message on every channel
+// subscribed and unsubscribes channels that are in moved when it gets a PING. the cluster layout comes from
+// shards.
+func newShardNode(t *testing.T, moved func() []string, shards func(w io.Writer)) *fakeServer {
+	return newFakeServer(t, func(w io.Writer, args []string) {
+		switch args[0] {
+		case "CLUSTER":
+			shards(w)
+		case "SSUBSCRIBE":
+			for _, channel := range args[1:] {
+				writePush(w, "ssubscribe", channel)
+			}
+
+			for _, channel := range args[1:] {
+				writePush(w, "smessage", channel, "hello")
+			}
+		case "SUNSUBSCRIBE":
+			for _, channel := range args[1:] {
+				writePush(w, "sunsubscribe", channel)
+			}
+		case "PING":
+			for _, channel := range moved() {
+				writePush(w, "sunsubscribe", channel)
+			}
+
+			io.WriteString(w, "+PONG\r\n")
+		}
+	})
+}
+
+func TestShardedPubSub_FollowsMigrations(t *testing.T) {
+	var (
+		migrated      int32
+		first, second *fakeServer
+	)
+
+	ordersSlot := int64(KeySlot("orders"))
+	shards := func(w io.Writer) {
+		if atomic.LoadInt32(&migrated) == 0 {
+			writeClusterShards(w, testShard{start: 0, end: 16383, address: first.Addr()})
+			return
+		}
+
+		layout := []testShard{{start: ordersSlot, end: ordersSlot, address: second.Addr()}}
+		if ordersSlot > 0 {
+			layout = append(layout, testShard{start: 0, end: ordersSlot - 1, address: first.Addr()})
+		}
+
+		if ordersSlot < clusterSlots-1 {
+			layout = append(layout, testShard{start: ordersSlot + 1, end: clusterSlots - 1, address: first.Addr()})
+		}
+
+		writeClusterShards(w, layout...)
+	}
+
+	first = newShardNode(t, func() []string {
+		if atomic.CompareAndSwapInt32(&migrated, 1, 2) {
+			return []string{"orders"}
+		}
+
+		return nil
+	}, shards)
+
+	second = newShardNode(t, func() []string {
+		return nil
+	}, shards)
+
+	client, err := NewClusterClient(context.Background(), ClusterOptions{Addresses: []string{first.Addr()}})
+	require.NoError(t, err)
+	defer client.Close()
+
+	s := client.ShardedPubSub(PubSubOptions{PingInterval: time.Millisecond * 20})
+	defer s.Close()
+
+	require.NoError(t, s.SSubscribe(context.Background(), "orders", "users"))
+
+	received := map[Message]bool{}
+	for x := 0; x < 2; x++ {
+		received[receiveShardedMessage(t, s)] = true
+	}
+
+	assert.Equal(t, map[Message]bool{
+		{Channel: "orders", Payload: "hello"}: true,
+		{Channel: "users", Payload: "hello"}:  true,
+	}, received)
+
+	// the slot for orders moves to the second node, the first one unsubscribes it on the next PING
+	atomic.StoreInt32(&migrated, 1)
+
+	assert.Equal(t, Message{Channel: "orders", Payload: "hello"}, receiveShardedMessage(t, s))
+	assert.Contains(t, second.Commands(), []string{"SSUBSCRIBE", "orders"})
+
+	require.NoError(t, s.SUnsubscribe("users", "orders"))
+
+	require.Eventually(t, func() bool {
+		return countCommands(first, "SUNSUBSCRIBE") == 1 && countCommands(second, "SUNSUBSCRIBE") == 1
+	}, time.Second, time.Millisecond*10)
+
+	assert.Contains(t, first.Commands(), []string{"SUNSUBSCRIBE", "users"})
+	assert.Contains(t, second.Commands(), []string{"SUNSUBSCRIBE", "orders"})
+}
+
+func TestShardedPubSub_Moved(t *testing.T) {
+	var (
+		seed, target *fakeServer
+		moved        int32
+	)
+
+	target = newShardNode(t, func() []string {
+		return nil
+	}, func(w io.Writer) {
+		writeClusterShards(w, testShard{start: 0, end: 16383, address: target.Addr()})
+	})
+
+	// the seed owns all slots until it refuses a subscription with MOVED
+	seed = newFakeServer(t, func(w io.Writer, args []string) {
+		switch args[0] {
+		case "CLUSTER":
+			if atomic.LoadInt32(&moved) == 1 {
+				writeClusterShards(w, testShard{start: 0, end: 16383, address: target.Addr()})
+			} else {
+				writeClusterShards(w, testShard{start: 0, end: 16383, address: seed.Addr()})
+			}
+		case "SSUBSCRIBE":
+			atomic.StoreInt32(&moved, 1)
+			io.WriteString(w, "-MOVED "+strconv.Itoa(KeySlot(args[1]))+" "+target.Addr()+"\r\n")
+		}
+	})
+
+	client, err := NewClusterClient(context.Background(), ClusterOptions{Addresses: []string{seed.Addr()}})
+	require.NoError(t, err)
+	defer client.Close()
+
+	s := client.ShardedPubSub(PubSubOptions{})
+	require.NoError(t, s.SSubscribe(context.Background(), "orders"))
+	assert.Equal(t, Message{Channel: "orders", Payload: "hello"}, receiveShardedMessage(t, s))
+
+	require.NoError(t, s.Close())
+
+	_, open := <-s.Messages()
+	assert.False(t, open)
+	assert.Equal(t, ErrPubSubClosed, s.SSubscribe(context.Background(), "orders"))
+}
+
+func receiveShardedMessage(t *testing.T, s *ShardedPubSub) Message {
+	t.Helper()
+
+	select {
+	case message := <-s.Messages():
+		return message
+	case <-time.After(time.Second * 2):
+		t.Fatal("no message received")
+	}
+
+	return Message{}
+}
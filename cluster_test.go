@@ -0,0 +1,276 @@
+package redis_client
+
+import (
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type testShard struct {
+	start   int64
+	end     int64
+	address string
+}
+
+// writeClusterShards writes a RESP2 `CLUSTER SHARDS` reply, every shard has a replica listed before its master.
+func writeClusterShards(w io.Writer, shards ...testShard) {
+	reply := make([]interface{}, 0, len(shards))
+	for _, shard := range shards {
+		host, port, _ := net.SplitHostPort(shard.address)
+		portNumber, _ := strconv.ParseInt(port, 10, 64)
+
+		reply = append(reply, []interface{}{
+			"slots", []interface{}{shard.start, shard.end},
+			"nodes", []interface{}{
+				[]interface{}{"id", "replica", "endpoint", host, "port", portNumber + 1000, "role", "replica", "health", "online"},
+				[]interface{}{"id", "master", "endpoint", host, "port", portNumber, "role", "master", "health", "online"},
+			},
+		})
+	}
+
+	writer := NewWriter(w)
+	writer.WriteArray(reply)
+	writer.Flush()
+}
+
+func countCommands(server *fakeServer, name string) int {
+	count := 0
+	for _, command := range server.Commands() {
+		if command[0] == name {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestClusterClient_ClusterSlots(t *testing.T) {
+	// miniredis only knows CLUSTER SLOTS and says it serves all slots
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	client, err := NewClusterClient(context.Background(), ClusterOptions{Addresses: []string{server.Addr()}})
+	require.NoError(t, err)
+	defer client.Close()
+	ctx := context.Background()
+
+	_, err = client.Set(ctx, "{user:1}:name", "joe", SetOptions{})
+	require.NoError(t, err)
+
+	_, err = client.Set(ctx, "{user:1}:email", "joe@example.com", SetOptions{})
+	require.NoError(t, err)
+
+	values, err := client.MGet(ctx, "{user:1}:name", "{user:1}:email")
+	require.NoError(t, err)
+	assert.Equal(t, []NullString{{String: "joe", Valid: true}, {String: "joe@example.com", Valid: true}}, values)
+
+	_, err = client.Do(ctx, "MGET", "a", "b")
+	assert.True(t, errors.Is(err, ErrCrossSlot))
+	assert.EqualError(t, err, "MGET has keys on slots 15495 and 3300: redis: keys don't map to the same slot")
+
+	result, err := client.Do(ctx, "PING")
+	require.NoError(t, err)
+	assert.Equal(t, "PONG", result.Content())
+}
+
+func TestClusterClient_Routes(t *testing.T) {
+	first, err := miniredis.Run()
+	require.NoError(t, err)
+	defer first.Close()
+
+	second, err := miniredis.Run()
+	require.NoError(t, err)
+	defer second.Close()
+
+	seed := newFakeServer(t, func(w io.Writer, args []string) {
+		writeClusterShards(w,
+			testShard{start: 0, end: 8191, address: first.Addr()},
+			testShard{start: 8192, end: 16383, address: second.Addr()},
+		)
+	})
+
+	client, err := NewClusterClient(context.Background(), ClusterOptions{Addresses: []string{seed.Addr()}})
+	require.NoError(t, err)
+	defer client.Close()
+	ctx := context.Background()
+
+	// a is on slot 15495 and b on slot 3300
+	_, err = client.Set(ctx, "a", "on second", SetOptions{})
+	require.NoError(t, err)
+
+	_, err = client.Set(ctx, "b", "on first", SetOptions{})
+	require.NoError(t, err)
+
+	first.CheckGet(t, "b", "on first")
+	second.CheckGet(t, "a", "on second")
+	assert.False(t, first.Exists("a"))
+	assert.False(t, second.Exists("b"))
+
+	value, err := client.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "on second", value)
+
+	assert.Equal(t, [][]string{{"CLUSTER", "SHARDS"}}, seed.Commands())
+}
+
+func TestClusterClient_Moved(t *testing.T) {
+	target, err := miniredis.Run()
+	require.NoError(t, err)
+	defer target.Close()
+
+	var (
+		seed  *fakeServer
+		moved int32
+	)
+
+	// the seed starts with all slots and moves them to target once a command arrives
+	seed = newFakeServer(t, func(w io.Writer, args []string) {
+		if args[0] == "CLUSTER" {
+			if atomic.LoadInt32(&moved) == 1 {
+				writeClusterShards(w, testShard{start: 0, end: 16383, address: target.Addr()})
+			} else {
+				writeClusterShards(w, testShard{start: 0, end: 16383, address: seed.Addr()})
+			}
+
+			return
+		}
+
+		atomic.StoreInt32(&moved, 1)
+		io.WriteString(w, "-MOVED "+strconv.Itoa(KeySlot(args[1]))+" "+target.Addr()+"\r\n")
+	})
+
+	client, err := NewClusterClient(context.Background(), ClusterOptions{Addresses: []string{seed.Addr()}})
+	require.NoError(t, err)
+	defer client.Close()
+	ctx := context.Background()
+
+	seedPool, err := client.node(seed.Addr())
+	require.NoError(t, err)
+
+	_, err = client.Set(ctx, "some-key", "value", SetOptions{})
+	require.NoError(t, err)
+	target.CheckGet(t, "some-key", "value")
+
+	// the seed is not a master on the new layout, so its connections were closed
+	_, err = seedPool.Acquire(ctx)
+	assert.Equal(t, ErrPoolClosed, err)
+	assert.Equal(t, 0, seedPool.Stats().TotalConns)
+
+	// the layout was loaded again so commands go straight to the new node
+	_, err = client.Set(ctx, "other-key", "value", SetOptions{})
+	require.NoError(t, err)
+	target.CheckGet(t, "other-key", "value")
+
+	assert.Equal(t, 1, countCommands(seed, "SET"))
+	assert.Equal(t, 2, countCommands(seed, "CLUSTER"))
+}
+
+func TestClusterClient_Ask(t *testing.T) {
+	target := newFakeServer(t, func(w io.Writer, args []string) {
+		switch args[0] {
+		case "ASKING":
+			io.WriteString(w, "+OK\r\n")
+		case "GET":
+			io.WriteString(w, "$8\r\nmigrated\r\n")
+		}
+	})
+
+	var seed *fakeServer
+	seed = newFakeServer(t, func(w io.Writer, args []string) {
+		if args[0] == "CLUSTER" {
+			writeClusterShards(w, testShard{start: 0, end: 16383, address: seed.Addr()})
+			return
+		}
+
+		_, port, _ := net.SplitHostPort(target.Addr())
+		io.WriteString(w, "-ASK "+strconv.Itoa(KeySlot(args[1]))+" :"+port+"\r\n")
+	})
+
+	client, err := NewClusterClient(context.Background(), ClusterOptions{Addresses: []string{seed.Addr()}})
+	require.NoError(t, err)
+	defer client.Close()
+	ctx := context.Background()
+
+	for x := 0; x < 2; x++ {
+		value, err := client.Get(ctx, "some-key")
+		require.NoError(t, err)
+		assert.Equal(t, "migrated", value)
+	}
+
+	// ASK doesn't change the layout, every command goes to the seed first
+	assert.Equal(t, 2, countCommands(seed, "GET"))
+	assert.Equal(t, 1, countCommands(seed, "CLUSTER"))
+	assert.Equal(t, [][]string{
+		{"ASKING"},
+		{"GET", "some-key"},
+		{"ASKING"},
+		{"GET", "some-key"},
+	}, target.Commands())
+}
+
+func TestClusterClient_MaxRedirects(t *testing.T) {
+	var seed *fakeServer
+	seed = newFakeServer(t, func(w io.Writer, args []string) {
+		if args[0] == "CLUSTER" {
+			writeClusterShards(w, testShard{start: 0, end: 16383, address: seed.Addr()})
+			return
+		}
+
+		io.WriteString(w, "-MOVED "+strconv.Itoa(KeySlot(args[1]))+" "+seed.Addr()+"\r\n")
+	})
+
+	client, err := NewClusterClient(context.Background(), ClusterOptions{Addresses: []string{seed.Addr()}, MaxRedirects: 2})
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Get(context.Background(), "some-key")
+	assert.True(t, IsMoved(err), "expected a MOVED error but got %v", err)
+	assert.Equal(t, 3, countCommands(seed, "GET"))
+}
+
+func TestNewClusterClient_Errors(t *testing.T) {
+	_, err := NewClusterClient(context.Background(), ClusterOptions{})
+	assert.EqualError(t, err, "at least one seed address is required to connect to a cluster")
+
+	seed := newFakeServer(t, func(w io.Writer, args []string) {
+		io.WriteString(w, "-ERR This instance has cluster support disabled\r\n")
+	})
+
+	_, err = NewClusterClient(context.Background(), ClusterOptions{Addresses: []string{seed.Addr()}})
+	assert.EqualError(t, err, "failed to load the cluster layout: CLUSTER SLOTS failed on "+seed.Addr()+": ERR This instance has cluster support disabled")
+}
+
+func TestParseClusterSlots(t *testing.T) {
+	input := "*2\r\n" +
+		"*4\r\n:0\r\n:5460\r\n*3\r\n$9\r\n127.0.0.1\r\n:30001\r\n$2\r\nid\r\n*3\r\n$9\r\n127.0.0.1\r\n:30004\r\n$2\r\nid\r\n" +
+		"*3\r\n:5461\r\n:16383\r\n*2\r\n$0\r\n\r\n:30002\r\n"
+
+	result, err := NewReader(strings.NewReader(input)).Read()
+	require.NoError(t, err)
+
+	ranges, err := parseClusterSlots(result, "10.0.0.1:30001")
+	require.NoError(t, err)
+	assert.Equal(t, []slotRange{
+		{start: 0, end: 5460, address: "127.0.0.1:30001"},
+		{start: 5461, end: 16383, address: "10.0.0.1:30002"},
+	}, ranges)
+
+	layout := newClusterLayout(ranges)
+	assert.Equal(t, []string{"127.0.0.1:30001", "10.0.0.1:30002"}, layout.masters)
+
+	address, ok := layout.address(5461)
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.1:30002", address)
+
+	_, ok = newClusterLayout([]slotRange{{start: 10, end: 20, address: "a"}}).address(21)
+	assert.False(t, ok)
+}
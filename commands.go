@@ -0,0 +1,249 @@
+package redis_client
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Commands has typed methods for redis commands, they build the arguments, send them with the Do of the
+// Client, Pool or Multiplexer they are embedded in and turn the reply into Go types. error replies from the
+// server are returned as errors and null replies as ErrNil.
+type Commands struct {
+	do func(ctx context.Context, args ...interface{}) (*Result, error)
+}
+
+// NullString is a string reply that can be null, like the values for missing keys on MGET.
+type NullString struct {
+	String string
+	Valid  bool
+}
+
+// ExpireCondition is the condition for EXPIRE and PEXPIRE to set the expiration, the empty condition always sets it.
+type ExpireCondition string
+
+const (
+	// ExpireNX only sets the expiration if the key has none.
+	ExpireNX ExpireCondition = "NX"
+	// ExpireXX only sets the expiration if the key already has one.
+	ExpireXX ExpireCondition = "XX"
+	// ExpireGT only sets the expiration if it is greater than the current one.
+	ExpireGT ExpireCondition = "GT"
+	// ExpireLT only sets the expiration if it is less than the current one.
+	ExpireLT ExpireCondition = "LT"
+)
+
+// Del removes the keys and returns how many of them existed.
+func (c *Commands) Del(ctx context.Context, keys ...string) (int64, error) {
+	return c.int64(ctx, "DEL", keys)
+}
+
+// Unlink removes the keys like Del but frees their memory in the background.
+func (c *Commands) Unlink(ctx context.Context, keys ...string) (int64, error) {
+	return c.int64(ctx, "UNLINK", keys)
+}
+
+// Exists returns how many of the keys exist, keys given more than once are counted more than once.
+func (c *Commands) Exists(ctx context.Context, keys ...string) (int64, error) {
+	return c.int64(ctx, "EXISTS", keys)
+}
+
+// Expire sets a timeout on the key, it returns false if the key doesn't exist or the condition was not met.
+// whole seconds are sent with EXPIRE and anything else with PEXPIRE, so timeouts under a second don't become
+// `EXPIRE key 0`, which removes the key right away.
+func (c *Commands) Expire(ctx context.Context, key string, expiration time.Duration, condition ExpireCondition) (bool, error) {
+	if expiration%time.Second != 0 {
+		return c.PExpire(ctx, key, expiration, condition)
+	}
+
+	return c.bool(ctx, expireArgs("EXPIRE", key, int64(expiration/time.Second), condition)...)
+}
+
+// PExpire sets a timeout in milliseconds on the key, it returns false if the key doesn't exist or the
+// condition was not met. timeouts under a millisecond fail instead of being sent as 0.
+func (c *Commands) PExpire(ctx context.Context, key string, expiration time.Duration, condition ExpireCondition) (bool, error) {
+	if err := checkExpiration(expiration); err != nil {
+		return false, err
+	}
+
+	return c.bool(ctx, expireArgs("PEXPIRE", key, expiration.Milliseconds(), condition)...)
+}
+
+// checkExpiration rejects timeouts under a millisecond, they would be sent as 0 which the server either
+// refuses or takes as a key that already expired.
+func checkExpiration(expiration time.Duration) error {
+	if expiration > 0 && expiration < time.Millisecond {
+		return fmt.Errorf("expiration of %v is under a millisecond", expiration)
+	}
+
+	return nil
+}
+
+func expireArgs(command string, key string, value int64, condition ExpireCondition) []interface{} {
+	args := []interface{}{command, key, value}
+	if condition != "" {
+		args = append(args, string(condition))
+	}
+
+	return args
+}
+
+// TTL returns the time to live of the key with a second precision. it returns ErrNoExpiration if the key has no
+// expiration and ErrNoKey if the key doesn't exist.
+func (c *Commands) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return c.duration(ctx, time.Second, "TTL", key)
+}
+
+// PTTL returns the time to live of the key with a millisecond precision. it returns ErrNoExpiration if the key
+// has no expiration and ErrNoKey if the key doesn't exist.
+func (c *Commands) PTTL(ctx context.Context, key string) (time.Duration, error) {
+	return c.duration(ctx, time.Millisecond, "PTTL", key)
+}
+
+// Persist removes the expiration from the key, it returns false if the key doesn't exist or has no expiration.
+func (c *Commands) Persist(ctx context.Context, key string) (bool, error) {
+	return c.bool(ctx, "PERSIST", key)
+}
+
+// Rename renames key to newKey, replacing newKey if it exists.
+func (c *Commands) Rename(ctx context.Context, key string, newKey string) error {
+	return c.status(ctx, "RENAME", key, newKey)
+}
+
+// Type returns the type of the value at key, like string, list or hash, or none if the key doesn't exist.
+func (c *Commands) Type(ctx context.Context, key string) (string, error) {
+	return c.string(ctx, "TYPE", key)
+}
+
+// Copy copies the value at source to destination, it returns false if destination exists and replace is false.
+func (c *Commands) Copy(ctx context.Context, source string, destination string, replace bool) (bool, error) {
+	args := []interface{}{"COPY", source, destination}
+	if replace {
+		args = append(args, "REPLACE")
+	}
+
+	return c.bool(ctx, args...)
+}
+
+func (c *Commands) result(ctx context.Context, args ...interface{}) (*Result, error) {
+	result, err := c.do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (c *Commands) status(ctx context.Context, args ...interface{}) error {
+	_, err := c.result(ctx, args...)
+	return err
+}
+
+func (c *Commands) int64(ctx context.Context, args ...interface{}) (int64, error) {
+	result, err := c.result(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.Int64()
+}
+
+func (c *Commands) bool(ctx context.Context, args ...interface{}) (bool, error) {
+	result, err := c.result(ctx, args...)
+	if err != nil {
+		return false, err
+	}
+
+	return result.Bool()
+}
+
+func (c *Commands) float64(ctx context.Context, args ...interface{}) (float64, error) {
+	result, err := c.result(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.Float64()
+}
+
+func (c *Commands) string(ctx context.Context, args ...interface{}) (string, error) {
+	result, err := c.result(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+
+	return stringValue(result.content)
+}
+
+func (c *Commands) duration(ctx context.Context, unit time.Duration, args ...interface{}) (time.Duration, error) {
+	result, err := c.result(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.Duration(unit)
+}
+
+func (c *Commands) nullStrings(ctx context.Context, args ...interface{}) ([]NullString, error) {
+	result, err := c.result(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return nullStringSlice(result)
+}
+
+func nullStringSlice(result *Result) ([]NullString, error) {
+	values, err := result.Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	strings := make([]NullString, 0, len(values))
+	for _, value := range values {
+		if value == nil {
+			strings = append(strings, NullString{})
+			continue
+		}
+
+		s, err := stringValue(value)
+		if err != nil {
+			return nil, err
+		}
+
+		strings = append(strings, NullString{String: s, Valid: true})
+	}
+
+	return strings, nil
+}
+
+func (c *Commands) strings(ctx context.Context, args ...interface{}) ([]string, error) {
+	result, err := c.result(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.StringSlice()
+}
+
+func (c *Commands) int64s(ctx context.Context, args ...interface{}) ([]int64, error) {
+	result, err := c.result(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Int64Slice()
+}
+
+func (c *Commands) stringMap(ctx context.Context, args ...interface{}) (map[string]string, error) {
+	result, err := c.result(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.StringMap()
+}
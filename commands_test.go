@@ -0,0 +1,131 @@
+package redis_client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestCommands_Keys(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	require.NoError(t, server.Set("a", "1"))
+	require.NoError(t, server.Set("b", "2"))
+	require.NoError(t, server.Set("c", "3"))
+
+	count, err := client.Exists(ctx, "a", "b", "missing")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, err = client.Del(ctx, "a", "missing")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	count, err = client.Unlink(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	keyType, err := client.Type(ctx, "c")
+	require.NoError(t, err)
+	assert.Equal(t, "string", keyType)
+
+	keyType, err = client.Type(ctx, "missing")
+	require.NoError(t, err)
+	assert.Equal(t, "none", keyType)
+
+	require.NoError(t, client.Rename(ctx, "c", "d"))
+	assert.True(t, server.Exists("d"))
+
+	err = client.Rename(ctx, "missing", "e")
+	assert.EqualError(t, err, "ERR no such key")
+}
+
+func TestCommands_Expirations(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	require.NoError(t, server.Set("some-key", "value"))
+
+	_, err := client.TTL(ctx, "some-key")
+	assert.Equal(t, ErrNoExpiration, err)
+
+	_, err = client.TTL(ctx, "missing")
+	assert.Equal(t, ErrNoKey, err)
+
+	set, err := client.Expire(ctx, "some-key", time.Minute, "")
+	require.NoError(t, err)
+	assert.True(t, set)
+
+	ttl, err := client.TTL(ctx, "some-key")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	set, err = client.PExpire(ctx, "some-key", time.Millisecond*1500, "")
+	require.NoError(t, err)
+	assert.True(t, set)
+
+	ttl, err = client.PTTL(ctx, "some-key")
+	require.NoError(t, err)
+	assert.Equal(t, time.Millisecond*1500, ttl)
+
+	set, err = client.Expire(ctx, "some-key", time.Millisecond*500, "")
+	require.NoError(t, err)
+	assert.True(t, set)
+	assert.True(t, server.Exists("some-key"))
+	assert.Equal(t, time.Millisecond*500, server.TTL("some-key"))
+
+	set, err = client.Expire(ctx, "missing", time.Minute, "")
+	require.NoError(t, err)
+	assert.False(t, set)
+
+	persisted, err := client.Persist(ctx, "some-key")
+	require.NoError(t, err)
+	assert.True(t, persisted)
+
+	_, err = client.TTL(ctx, "some-key")
+	assert.Equal(t, ErrNoExpiration, err)
+}
+
+func TestCommands_Arguments(t *testing.T) {
+	server := newFakeServer(t, func(w io.Writer, args []string) {
+		io.WriteString(w, ":1\r\n")
+	})
+
+	client, err := Connect(context.Background(), server.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx := context.Background()
+
+	_, err = client.Expire(ctx, "some-key", time.Minute, ExpireNX)
+	require.NoError(t, err)
+
+	_, err = client.PExpire(ctx, "some-key", time.Second, ExpireGT)
+	require.NoError(t, err)
+
+	// timeouts under a second go as PEXPIRE so they don't become `EXPIRE some-key 0`
+	_, err = client.Expire(ctx, "some-key", time.Millisecond*500, "")
+	require.NoError(t, err)
+
+	_, err = client.Expire(ctx, "some-key", time.Microsecond*500, "")
+	assert.EqualError(t, err, "expiration of 500µs is under a millisecond")
+
+	copied, err := client.Copy(ctx, "source", "destination", false)
+	require.NoError(t, err)
+	assert.True(t, copied)
+
+	_, err = client.Copy(ctx, "source", "destination", true)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]string{
+		{"EXPIRE", "some-key", "60", "NX"},
+		{"PEXPIRE", "some-key", "1000", "GT"},
+		{"PEXPIRE", "some-key", "500"},
+		{"COPY", "source", "destination"},
+		{"COPY", "source", "destination", "REPLACE"},
+	}, server.Commands())
+}
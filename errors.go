@@ -0,0 +1,147 @@
+package redis_client
+
+import (
+	"errors"
+	"strings"
+)
+
+// error prefixes sent by the server, the prefix is the first word of the error reply.
+const (
+	PrefixErr         = "ERR"
+	PrefixWrongType   = "WRONGTYPE"
+	PrefixMoved       = "MOVED"
+	PrefixAsk         = "ASK"
+	PrefixNoAuth      = "NOAUTH"
+	PrefixNoPerm      = "NOPERM"
+	PrefixLoading     = "LOADING"
+	PrefixBusy        = "BUSY"
+	PrefixTryAgain    = "TRYAGAIN"
+	PrefixClusterDown = "CLUSTERDOWN"
+	PrefixMasterDown  = "MASTERDOWN"
+	PrefixReadOnly    = "READONLY"
+	PrefixExecAbort   = "EXECABORT"
+	PrefixNoScript    = "NOSCRIPT"
+)
+
+var (
+	_ error = &RedisError{}
+
+	// retryablePrefixes are errors for conditions that go away by themselves, like a server loading its
+	// dataset from disk or a cluster slot being migrated, so the same command can be sent again later.
+	retryablePrefixes = map[string]bool{
+		PrefixLoading:     true,
+		PrefixBusy:        true,
+		PrefixTryAgain:    true,
+		PrefixClusterDown: true,
+		PrefixMasterDown:  true,
+	}
+)
+
+// RedisError is an error reply sent by the server. errors start with an all uppercase word that says what kind
+// of error it is, like `ERR` or `WRONGTYPE`, which is the Prefix, and the rest of the line is the Message.
+// errors that don't start with an uppercase word have an empty Prefix.
+type RedisError struct {
+	Prefix  string
+	Message string
+}
+
+// parseRedisError splits an error reply into its prefix and message.
+func parseRedisError(line string) *RedisError {
+	prefix := line
+	message := ""
+	if index := strings.IndexByte(line, ' '); index >= 0 {
+		prefix = line[:index]
+		message = line[index+1:]
+	}
+
+	if !isErrorPrefix(prefix) {
+		return &RedisError{
+			Message: line,
+		}
+	}
+
+	return &RedisError{
+		Prefix:  prefix,
+		Message: message,
+	}
+}
+
+func isErrorPrefix(value string) bool {
+	if len(value) == 0 {
+		return false
+	}
+
+	for x := 0; x < len(value); x++ {
+		if value[x] < 'A' || value[x] > 'Z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Error returns the error exactly as the server sent it.
+func (e *RedisError) Error() string {
+	switch {
+	case e.Prefix == "":
+		return e.Message
+	case e.Message == "":
+		return e.Prefix
+	default:
+		return e.Prefix + " " + e.Message
+	}
+}
+
+// Is matches other RedisErrors with the same prefix, so errors.Is(err, &RedisError{Prefix: PrefixMoved})
+// is true for any MOVED error.
+func (e *RedisError) Is(target error) bool {
+	other, ok := target.(*RedisError)
+	if !ok {
+		return false
+	}
+
+	return other.Prefix == e.Prefix && (other.Message == "" || other.Message == e.Message)
+}
+
+// HasPrefix returns true if err is, or wraps, a RedisError with this prefix.
+func HasPrefix(err error, prefix string) bool {
+	var redisErr *RedisError
+	if !errors.As(err, &redisErr) {
+		return false
+	}
+
+	return redisErr.Prefix == prefix
+}
+
+// IsMoved returns true for MOVED errors, the slot for the key lives on another cluster node.
+func IsMoved(err error) bool {
+	return HasPrefix(err, PrefixMoved)
+}
+
+// IsAsk returns true for ASK errors, the slot for the key is being migrated and this command should be sent
+// to the node in the error after an ASKING command.
+func IsAsk(err error) bool {
+	return HasPrefix(err, PrefixAsk)
+}
+
+// IsReadOnly returns true for READONLY errors, a write was sent to a replica, usually because a failover
+// happened and this node isn't the primary anymore.
+func IsReadOnly(err error) bool {
+	return HasPrefix(err, PrefixReadOnly)
+}
+
+// IsNoScript returns true for NOSCRIPT errors, the script sent with EVALSHA isn't loaded on the server.
+func IsNoScript(err error) bool {
+	return HasPrefix(err, PrefixNoScript)
+}
+
+// IsRetryable returns true for errors caused by temporary conditions on the server (LOADING, BUSY, TRYAGAIN,
+// CLUSTERDOWN and MASTERDOWN), the command was not executed and can be sent again after a while.
+func IsRetryable(err error) bool {
+	var redisErr *RedisError
+	if !errors.As(err, &redisErr) {
+		return false
+	}
+
+	return retryablePrefixes[redisErr.Prefix]
+}
@@ -0,0 +1,138 @@
+package redis_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+func TestParseRedisError(t *testing.T) {
+	tt := []struct {
+		input  string
+		result *RedisError
+	}{
+		{
+			input:  "ERR unknown command 'foobar'",
+			result: &RedisError{Prefix: PrefixErr, Message: "unknown command 'foobar'"},
+		},
+		{
+			input:  "MOVED 3999 127.0.0.1:6381",
+			result: &RedisError{Prefix: PrefixMoved, Message: "3999 127.0.0.1:6381"},
+		},
+		{
+			input:  "CLUSTERDOWN",
+			result: &RedisError{Prefix: PrefixClusterDown},
+		},
+		{
+			input:  "Error with no prefix",
+			result: &RedisError{Message: "Error with no prefix"},
+		},
+		{
+			input:  "",
+			result: &RedisError{},
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.input, func(t *testing.T) {
+			err := parseRedisError(ts.input)
+			assert.Equal(t, ts.result, err)
+			assert.Equal(t, ts.input, err.Error())
+		})
+	}
+}
+
+func TestRedisError_Predicates(t *testing.T) {
+	tt := []struct {
+		input     string
+		moved     bool
+		ask       bool
+		readOnly  bool
+		noScript  bool
+		retryable bool
+	}{
+		{
+			input: "MOVED 3999 127.0.0.1:6381",
+			moved: true,
+		},
+		{
+			input: "ASK 3999 127.0.0.1:6381",
+			ask:   true,
+		},
+		{
+			input:    "READONLY You can't write against a read only replica.",
+			readOnly: true,
+		},
+		{
+			input:    "NOSCRIPT No matching script. Please use EVAL.",
+			noScript: true,
+		},
+		{
+			input:     "LOADING Redis is loading the dataset in memory",
+			retryable: true,
+		},
+		{
+			input:     "BUSY Redis is busy running a script.",
+			retryable: true,
+		},
+		{
+			input:     "TRYAGAIN Multiple keys request during rehashing of slot",
+			retryable: true,
+		},
+		{
+			input:     "CLUSTERDOWN The cluster is down",
+			retryable: true,
+		},
+		{
+			input: "WRONGTYPE Operation against a key holding the wrong kind of value",
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.input, func(t *testing.T) {
+			// predicates see through wrapped errors
+			err := fmt.Errorf("command failed: %w", parseRedisError(ts.input))
+
+			assert.Equal(t, ts.moved, IsMoved(err))
+			assert.Equal(t, ts.ask, IsAsk(err))
+			assert.Equal(t, ts.readOnly, IsReadOnly(err))
+			assert.Equal(t, ts.noScript, IsNoScript(err))
+			assert.Equal(t, ts.retryable, IsRetryable(err))
+		})
+	}
+
+	assert.False(t, IsRetryable(errors.New("LOADING this is not a redis error")))
+	assert.False(t, IsMoved(nil))
+}
+
+func TestRedisError_Is(t *testing.T) {
+	err := parseRedisError("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+	assert.True(t, errors.Is(err, &RedisError{Prefix: PrefixWrongType}))
+	assert.True(t, errors.Is(err, &RedisError{Prefix: PrefixWrongType, Message: "Operation against a key holding the wrong kind of value"}))
+	assert.False(t, errors.Is(err, &RedisError{Prefix: PrefixWrongType, Message: "something else"}))
+	assert.False(t, errors.Is(err, &RedisError{Prefix: PrefixErr}))
+}
+
+func TestClient_DoRedisError(t *testing.T) {
+	server := newFakeServer(t, func(w io.Writer, args []string) {
+		io.WriteString(w, "-NOPERM this user has no permissions to run the '"+args[0]+"' command\r\n")
+	})
+
+	client, err := Connect(context.Background(), server.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+
+	result, err := client.Do(context.Background(), "FLUSHALL")
+	require.NoError(t, err)
+
+	var redisErr *RedisError
+	require.True(t, errors.As(result.Err(), &redisErr))
+	assert.Equal(t, PrefixNoPerm, redisErr.Prefix)
+	assert.Equal(t, "this user has no permissions to run the 'FLUSHALL' command", redisErr.Message)
+	assert.True(t, HasPrefix(result.Err(), PrefixNoPerm))
+}
@@ -0,0 +1,338 @@
+package redis_client
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+const (
+	// switchMasterChannel is where sentinels announce a new master, messages are
+	// `<master name> <old ip> <old port> <new ip> <new port>`
+	switchMasterChannel = "+switch-master"
+)
+
+var (
+	_ io.Closer = &FailoverClient{}
+
+	// ErrFailoverClosed is returned when using a closed FailoverClient.
+	ErrFailoverClosed = errors.New("redis: failover client is closed")
+)
+
+// FailoverOptions configures a FailoverClient.
+type FailoverOptions struct {
+	// MasterName is the name the sentinels monitor the master as.
+	MasterName string
+	// SentinelAddresses are the sentinels asked for the master address, in order, only one of them has to be up.
+	SentinelAddresses []string
+	// SentinelOptions is how connections to the sentinels are made, Address is replaced with the address of
+	// each sentinel.
+	SentinelOptions Options
+	// PoolOptions configures the pool of connections to the master, Options.Address is replaced with the
+	// address of the current master and Dial is not used.
+	PoolOptions PoolOptions
+}
+
+// FailoverClient sends commands to the master of a redis deployment managed by sentinels. the address of the
+// master comes from the sentinels with `SENTINEL get-master-addr-by-name` and is only used once `ROLE` on the
+// server says it is a master.
+//
+// the client subscribes to `+switch-master` on every sentinel and when the master changes commands go to the
+// new master and the pooled connections to the old one are closed. commands that fail with READONLY, because
+// the master was demoted before the sentinels said so, make the client ask the sentinels again and are sent
+// to the new master. commands that fail with connection errors also make it ask the sentinels again, but are
+// not sent again as they might have been executed. commands stopped by their context don't ask the sentinels.
+type FailoverClient struct {
+	Commands
+	options FailoverOptions
+	ctx     context.Context
+	cancel  context.CancelFunc
+	loops   sync.WaitGroup
+
+	mutex   sync.RWMutex
+	address string
+	pool    *Pool
+	closed  bool
+	// discoveries counts the times the sentinels were asked after a command failed, commands that failed
+	// while the sentinels were being asked share the answer instead of asking again
+	discoveries uint64
+
+	// failoverMutex makes commands that failed at the same time ask the sentinels only once
+	failoverMutex sync.Mutex
+}
+
+// NewFailoverClient finds the master through the sentinels and returns a client for it.
+func NewFailoverClient(ctx context.Context, options FailoverOptions) (*FailoverClient, error) {
+	if options.MasterName == "" {
+		return nil, errors.New("a master name is required to connect through sentinels")
+	}
+
+	if len(options.SentinelAddresses) == 0 {
+		return nil, errors.New("at least one sentinel address is required to find the master")
+	}
+
+	options.PoolOptions.Dial = nil
+
+	c := &FailoverClient{
+		options: options,
+	}
+	c.Commands = Commands{do: c.Do}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	address, err := c.discover(ctx)
+	if err != nil {
+		c.cancel()
+		return nil, err
+	}
+
+	c.address = address
+	c.pool = c.newPool(address)
+
+	for _, sentinel := range options.SentinelAddresses {
+		c.loops.Add(1)
+		go c.watch(sentinel)
+	}
+
+	return c, nil
+}
+
+// Do sends the command to the current master and waits for its reply.
+func (c *FailoverClient) Do(ctx context.Context, args ...interface{}) (*Result, error) {
+	for attempt := 0; ; attempt++ {
+		pool, discoveries, err := c.currentPool()
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := pool.Do(ctx, args...)
+		if err == nil && !HasPrefix(result.Err(), PrefixReadOnly) {
+			return result, nil
+		}
+
+		// the command ran out of time, that says nothing about the master
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return result, err
+		}
+
+		// READONLY and a pool closed by a switch mean the command was not executed, so it is safe to send
+		// it again to the new master
+		retry := errors.Is(err, ErrPoolClosed) || err == nil
+		switched := c.failover(ctx, pool, discoveries)
+
+		if !retry || !switched || attempt > 0 {
+			return result, err
+		}
+	}
+}
+
+// MasterAddress returns the address of the master commands are sent to.
+func (c *FailoverClient) MasterAddress() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.address
+}
+
+// Close stops watching the sentinels and closes the connections to the master.
+func (c *FailoverClient) Close() error {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil
+	}
+
+	c.closed = true
+	pool := c.pool
+	c.mutex.Unlock()
+
+	c.cancel()
+	c.loops.Wait()
+
+	return pool.Close()
+}
+
+// currentPool returns the pool for the current master and how many times the sentinels were asked so far.
+func (c *FailoverClient) currentPool() (*Pool, uint64, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.closed {
+		return nil, 0, ErrFailoverClosed
+	}
+
+	return c.pool, c.discoveries, nil
+}
+
+func (c *FailoverClient) newPool(address string) *Pool {
+	options := c.options.PoolOptions
+	options.Options = options.Options.withAddress(address)
+
+	return NewPool(options)
+}
+
+// failover asks the sentinels for the master after a command failed on stale, it returns true if commands
+// go to a different master now. discoveries is the count from when the command started, if the sentinels
+// were asked since then the command failed before that answer and it is used instead of asking again.
+func (c *FailoverClient) failover(ctx context.Context, stale *Pool, discoveries uint64) bool {
+	c.failoverMutex.Lock()
+	defer c.failoverMutex.Unlock()
+
+	c.mutex.RLock()
+	current, latest := c.pool, c.discoveries
+	c.mutex.RUnlock()
+
+	if current != stale {
+		return true
+	}
+
+	if latest != discoveries {
+		return false
+	}
+
+	address, err := c.discover(ctx)
+
+	c.mutex.Lock()
+	c.discoveries++
+	c.mutex.Unlock()
+
+	if err != nil {
+		return false
+	}
+
+	return c.switchMaster(address)
+}
+
+// switchMaster makes commands go to the master at address and closes the connections to the old master,
+// connections in use are closed once they are returned to the old pool.
+func (c *FailoverClient) switchMaster(address string) bool {
+	c.mutex.Lock()
+	if c.closed || c.address == address {
+		c.mutex.Unlock()
+		return false
+	}
+
+	old := c.pool
+	c.address = address
+	c.pool = c.newPool(address)
+	c.mutex.Unlock()
+
+	old.Close()
+
+	return true
+}
+
+// discover asks the sentinels for the master, in order, and returns the first address that is a master.
+func (c *FailoverClient) discover(ctx context.Context) (string, error) {
+	var lastErr error
+	for _, sentinel := range c.options.SentinelAddresses {
+		address, err := c.askSentinel(ctx, sentinel)
+		if err == nil {
+			err = c.checkRole(ctx, address)
+		}
+
+		if err == nil {
+			return address, nil
+		}
+
+		lastErr = err
+	}
+
+	return "", errors.Wrapf(lastErr, "failed to find the master for %v", c.options.MasterName)
+}
+
+// askSentinel returns the master address the sentinel knows about.
+func (c *FailoverClient) askSentinel(ctx context.Context, sentinel string) (string, error) {
+	client, err := ConnectWithOptions(ctx, c.options.SentinelOptions.withAddress(sentinel))
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	result, err := client.result(ctx, "SENTINEL", "get-master-addr-by-name", c.options.MasterName)
+	if err != nil {
+		return "", errors.Wrapf(err, "SENTINEL failed on %v", sentinel)
+	}
+
+	if result.Content() == nil {
+		return "", fmt.Errorf("sentinel %v doesn't know master %v", sentinel, c.options.MasterName)
+	}
+
+	hostAndPort, err := result.StringSlice()
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid SENTINEL reply from %v", sentinel)
+	}
+
+	if len(hostAndPort) != 2 {
+		return "", fmt.Errorf("SENTINEL reply from %v should be a host and a port but was %v", sentinel, hostAndPort)
+	}
+
+	return net.JoinHostPort(hostAndPort[0], hostAndPort[1]), nil
+}
+
+// checkRole makes sure the server at address is a master, sentinels might not have noticed a failover yet.
+func (c *FailoverClient) checkRole(ctx context.Context, address string) error {
+	client, err := ConnectWithOptions(ctx, c.options.PoolOptions.Options.withAddress(address))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	result, err := client.result(ctx, "ROLE")
+	if err != nil {
+		return errors.Wrapf(err, "ROLE failed on %v", address)
+	}
+
+	role, _, err := result.Index(0).String()
+	if err != nil {
+		return errors.Wrapf(err, "invalid ROLE reply from %v", address)
+	}
+
+	if role != "master" {
+		return fmt.Errorf("%v is not a master, its role is %v", address, role)
+	}
+
+	return nil
+}
+
+// watch subscribes to `+switch-master` on the sentinel and switches to the new master when it changes, it
+// keeps trying to subscribe, waiting longer after each failure, until the client is closed.
+func (c *FailoverClient) watch(sentinel string) {
+	defer c.loops.Done()
+
+	retryWithBackoff(c.ctx, func() bool {
+		pubSub, err := NewPubSub(c.ctx, PubSubOptions{
+			Options: c.options.SentinelOptions.withAddress(sentinel),
+		})
+		if err != nil {
+			return false
+		}
+		defer pubSub.Close()
+
+		if err := pubSub.Subscribe(c.ctx, switchMasterChannel); err != nil {
+			return false
+		}
+
+		// the PubSub reconnects by itself, this only returns once the client is closed
+		c.receiveSwitches(pubSub)
+		return true
+	})
+}
+
+// receiveSwitches switches to the masters announced on the subscription until the client is closed.
+func (c *FailoverClient) receiveSwitches(pubSub *PubSub) {
+	for {
+		select {
+		case message := <-pubSub.Messages():
+			fields := strings.Fields(message.Payload)
+			if len(fields) == 5 && fields[0] == c.options.MasterName {
+				c.switchMaster(net.JoinHostPort(fields[3], fields[4]))
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
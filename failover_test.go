@@ -0,0 +1,302 @@
+package redis_client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeSentinel answers `SENTINEL get-master-addr-by-name mymaster` with the master address and keeps the
+// connections subscribed to `+switch-master` to announce new masters on.
+type fakeSentinel struct {
+	*fakeServer
+	mutex       sync.Mutex
+	master      string
+	subscribers []io.Writer
+}
+
+func newFakeSentinel(t *testing.T, master string) *fakeSentinel {
+	s := &fakeSentinel{
+		master: master,
+	}
+
+	s.fakeServer = newFakeServer(t, func(w io.Writer, args []string) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		switch args[0] {
+		case "SENTINEL":
+			if args[2] != "mymaster" || s.master == "" {
+				io.WriteString(w, "*-1\r\n")
+				return
+			}
+
+			host, port, _ := net.SplitHostPort(s.master)
+			writer := NewWriter(w)
+			writer.WriteArray([]interface{}{host, port})
+			writer.Flush()
+		case "SUBSCRIBE":
+			s.subscribers = append(s.subscribers, w)
+			io.WriteString(w, "*3\r\n$9\r\nsubscribe\r\n$14\r\n+switch-master\r\n:1\r\n")
+		case "PING":
+			io.WriteString(w, "+PONG\r\n")
+		}
+	})
+
+	return s
+}
+
+// setMaster changes the master without telling the subscribers.
+func (s *fakeSentinel) setMaster(address string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.master = address
+}
+
+// switchMaster changes the master and announces it to the subscribers, like sentinels do after a failover.
+func (s *fakeSentinel) switchMaster(address string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	oldHost, oldPort, _ := net.SplitHostPort(s.master)
+	newHost, newPort, _ := net.SplitHostPort(address)
+	s.master = address
+
+	payload := strings.Join([]string{"mymaster", oldHost, oldPort, newHost, newPort}, " ")
+	for _, w := range s.subscribers {
+		writer := NewWriter(w)
+		writer.WriteArray([]interface{}{"message", switchMasterChannel, payload})
+		writer.Flush()
+	}
+}
+
+func (s *fakeSentinel) subscribed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.subscribers) > 0
+}
+
+// newFakeRedis answers ROLE with the role and GET with the name of the server. when readOnly is set SET fails
+// with READONLY and ROLE says the server is a replica.
+func newFakeRedis(t *testing.T, name string, readOnly *int32) *fakeServer {
+	return newFakeServer(t, func(w io.Writer, args []string) {
+		demoted := readOnly != nil && atomic.LoadInt32(readOnly) == 1
+
+		switch args[0] {
+		case "ROLE":
+			role := "master"
+			if demoted {
+				role = "slave"
+			}
+
+			writer := NewWriter(w)
+			writer.WriteArray([]interface{}{role, int64(0), []interface{}{}})
+			writer.Flush()
+		case "GET":
+			io.WriteString(w, "$"+strconv.Itoa(len(name))+"\r\n"+name+"\r\n")
+		case "SET":
+			if demoted {
+				io.WriteString(w, "-READONLY You can't write against a read only replica.\r\n")
+			} else {
+				io.WriteString(w, "+OK\r\n")
+			}
+		}
+	})
+}
+
+func TestFailoverClient_Discovery(t *testing.T) {
+	master := newFakeRedis(t, "first", nil)
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	down := listener.Addr().String()
+	listener.Close()
+
+	unknown := newFakeSentinel(t, "")
+	sentinel := newFakeSentinel(t, master.Addr())
+
+	client, err := NewFailoverClient(context.Background(), FailoverOptions{
+		MasterName:        "mymaster",
+		SentinelAddresses: []string{down, unknown.Addr(), sentinel.Addr()},
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	value, err := client.Get(context.Background(), "some-key")
+	require.NoError(t, err)
+	assert.Equal(t, "first", value)
+	assert.Equal(t, master.Addr(), client.MasterAddress())
+
+	assert.Contains(t, sentinel.Commands(), []string{"SENTINEL", "get-master-addr-by-name", "mymaster"})
+	assert.Equal(t, []string{"ROLE"}, master.Commands()[0])
+
+	require.Eventually(t, func() bool {
+		return sentinel.subscribed() && unknown.subscribed()
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestFailoverClient_SwitchMaster(t *testing.T) {
+	first := newFakeRedis(t, "first", nil)
+	second := newFakeRedis(t, "second", nil)
+	sentinel := newFakeSentinel(t, first.Addr())
+
+	client, err := NewFailoverClient(context.Background(), FailoverOptions{
+		MasterName:        "mymaster",
+		SentinelAddresses: []string{sentinel.Addr()},
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	ctx := context.Background()
+
+	value, err := client.Get(ctx, "some-key")
+	require.NoError(t, err)
+	assert.Equal(t, "first", value)
+
+	old, _, err := client.currentPool()
+	require.NoError(t, err)
+
+	require.Eventually(t, sentinel.subscribed, time.Second, time.Millisecond*10)
+	sentinel.switchMaster(second.Addr())
+
+	require.Eventually(t, func() bool {
+		return client.MasterAddress() == second.Addr()
+	}, time.Second, time.Millisecond*10)
+
+	value, err = client.Get(ctx, "some-key")
+	require.NoError(t, err)
+	assert.Equal(t, "second", value)
+
+	_, err = old.Acquire(ctx)
+	assert.Equal(t, ErrPoolClosed, err)
+	assert.Equal(t, 0, old.Stats().TotalConns)
+}
+
+func TestFailoverClient_ReadOnly(t *testing.T) {
+	var demoted int32
+
+	first := newFakeRedis(t, "first", &demoted)
+	second := newFakeRedis(t, "second", nil)
+	sentinel := newFakeSentinel(t, first.Addr())
+
+	client, err := NewFailoverClient(context.Background(), FailoverOptions{
+		MasterName:        "mymaster",
+		SentinelAddresses: []string{sentinel.Addr()},
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	ctx := context.Background()
+
+	// the first master is demoted before the sentinels announce it
+	atomic.StoreInt32(&demoted, 1)
+	sentinel.setMaster(second.Addr())
+
+	result, err := client.Do(ctx, "SET", "some-key", "value")
+	require.NoError(t, err)
+	require.NoError(t, result.Err())
+
+	assert.Equal(t, second.Addr(), client.MasterAddress())
+	assert.Equal(t, 1, countCommands(first, "SET"))
+	assert.Contains(t, second.Commands(), []string{"SET", "some-key", "value"})
+}
+
+func TestFailoverClient_SharedDiscovery(t *testing.T) {
+	const commands = 10
+
+	var (
+		mutex    sync.Mutex
+		received int
+		replies  = make(chan struct{})
+	)
+
+	// the master refuses writes but is still the master, so asking the sentinels doesn't change anything
+	master := newFakeServer(t, func(w io.Writer, args []string) {
+		switch args[0] {
+		case "ROLE":
+			io.WriteString(w, "*3\r\n$6\r\nmaster\r\n:0\r\n*0\r\n")
+		case "SET":
+			// all commands fail at the same time
+			mutex.Lock()
+			if received++; received == commands {
+				close(replies)
+			}
+			mutex.Unlock()
+
+			<-replies
+			io.WriteString(w, "-READONLY You can't write against a read only replica.\r\n")
+		}
+	})
+	sentinel := newFakeSentinel(t, master.Addr())
+
+	client, err := NewFailoverClient(context.Background(), FailoverOptions{
+		MasterName:        "mymaster",
+		SentinelAddresses: []string{sentinel.Addr()},
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for x := 0; x < commands; x++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result, err := client.Do(context.Background(), "SET", "some-key", "value")
+			assert.NoError(t, err)
+			assert.True(t, HasPrefix(result.Err(), PrefixReadOnly))
+		}()
+	}
+	wg.Wait()
+
+	// once when the client was created and once for all the commands
+	assert.Equal(t, 2, countCommands(sentinel.fakeServer, "SENTINEL"))
+	assert.Equal(t, master.Addr(), client.MasterAddress())
+
+	// commands that ran out of time don't ask the sentinels
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = client.Do(ctx, "SET", "some-key", "value")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 2, countCommands(sentinel.fakeServer, "SENTINEL"))
+}
+
+func TestNewFailoverClient_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := NewFailoverClient(ctx, FailoverOptions{SentinelAddresses: []string{"localhost:26379"}})
+	assert.EqualError(t, err, "a master name is required to connect through sentinels")
+
+	_, err = NewFailoverClient(ctx, FailoverOptions{MasterName: "mymaster"})
+	assert.EqualError(t, err, "at least one sentinel address is required to find the master")
+
+	demoted := int32(1)
+	replica := newFakeRedis(t, "replica", &demoted)
+	sentinel := newFakeSentinel(t, replica.Addr())
+
+	_, err = NewFailoverClient(ctx, FailoverOptions{MasterName: "mymaster", SentinelAddresses: []string{sentinel.Addr()}})
+	assert.EqualError(t, err, "failed to find the master for mymaster: "+replica.Addr()+" is not a master, its role is slave")
+
+	master := newFakeRedis(t, "master", nil)
+	sentinel = newFakeSentinel(t, master.Addr())
+
+	client, err := NewFailoverClient(ctx, FailoverOptions{
+		MasterName:        "mymaster",
+		SentinelAddresses: []string{sentinel.Addr()},
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	require.NoError(t, client.Close())
+
+	_, err = client.Get(ctx, "some-key")
+	assert.Equal(t, ErrFailoverClosed, err)
+}
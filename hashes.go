@@ -0,0 +1,54 @@
+package redis_client
+
+import (
+	"context"
+)
+
+// HSet sets fields on the hash at key and returns how many fields were added, fields that already existed
+// are updated but not counted. fieldsAndValues are field and value pairs or maps of fields to values, use
+// StructArgs to set the fields of a struct.
+func (c *Commands) HSet(ctx context.Context, key string, fieldsAndValues ...interface{}) (int64, error) {
+	return c.int64(ctx, "HSET", key, fieldsAndValues)
+}
+
+// HGet returns the value of field or ErrNil if the field or the hash don't exist.
+func (c *Commands) HGet(ctx context.Context, key string, field string) (string, error) {
+	return c.string(ctx, "HGET", key, field)
+}
+
+// HMGet returns the values for all fields, missing fields are not Valid.
+func (c *Commands) HMGet(ctx context.Context, key string, fields ...string) ([]NullString, error) {
+	return c.nullStrings(ctx, "HMGET", key, fields)
+}
+
+// HGetAll returns all fields and values of the hash, the map is empty if the hash doesn't exist. to read
+// the hash into a struct use Do and Result.Scan.
+func (c *Commands) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return c.stringMap(ctx, "HGETALL", key)
+}
+
+// HIncrBy increments the integer at field by increment and returns the new value.
+func (c *Commands) HIncrBy(ctx context.Context, key string, field string, increment int64) (int64, error) {
+	return c.int64(ctx, "HINCRBY", key, field, increment)
+}
+
+// HDel removes the fields from the hash and returns how many of them existed.
+func (c *Commands) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
+	return c.int64(ctx, "HDEL", key, fields)
+}
+
+// HExists returns true if the field exists on the hash.
+func (c *Commands) HExists(ctx context.Context, key string, field string) (bool, error) {
+	return c.bool(ctx, "HEXISTS", key, field)
+}
+
+// HLen returns the number of fields on the hash.
+func (c *Commands) HLen(ctx context.Context, key string) (int64, error) {
+	return c.int64(ctx, "HLEN", key)
+}
+
+// HRandField returns up to count random fields from the hash, a negative count allows the same field to be
+// returned more than once.
+func (c *Commands) HRandField(ctx context.Context, key string, count int64) ([]string, error) {
+	return c.strings(ctx, "HRANDFIELD", key, count)
+}
@@ -0,0 +1,91 @@
+package redis_client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+func TestCommands_Hashes(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	added, err := client.HSet(ctx, "user", "name", "joe", map[string]interface{}{"age": 30, "admin": true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), added)
+
+	name, err := client.HGet(ctx, "user", "name")
+	require.NoError(t, err)
+	assert.Equal(t, "joe", name)
+
+	_, err = client.HGet(ctx, "user", "missing")
+	assert.Equal(t, ErrNil, err)
+
+	values, err := client.HMGet(ctx, "user", "name", "missing", "age")
+	require.NoError(t, err)
+	assert.Equal(t, []NullString{{String: "joe", Valid: true}, {}, {String: "30", Valid: true}}, values)
+
+	all, err := client.HGetAll(ctx, "user")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"name": "joe", "age": "30", "admin": "1"}, all)
+
+	all, err = client.HGetAll(ctx, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, all)
+
+	age, err := client.HIncrBy(ctx, "user", "age", 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(32), age)
+
+	exists, err := client.HExists(ctx, "user", "admin")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	removed, err := client.HDel(ctx, "user", "admin", "missing")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	exists, err = client.HExists(ctx, "user", "admin")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	length, err := client.HLen(ctx, "user")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), length)
+}
+
+func TestCommands_HSetStruct(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	args, err := StructArgs(scanUser{Name: "joe", Age: 30})
+	require.NoError(t, err)
+
+	_, err = client.HSet(ctx, "user", args...)
+	require.NoError(t, err)
+
+	result, err := client.Do(ctx, "HGETALL", "user")
+	require.NoError(t, err)
+
+	user := scanUser{}
+	require.NoError(t, result.Scan(&user))
+	assert.Equal(t, "joe", user.Name)
+	assert.Equal(t, 30, user.Age)
+}
+
+func TestCommands_HRandField(t *testing.T) {
+	server := newFakeServer(t, func(w io.Writer, args []string) {
+		io.WriteString(w, "*2\r\n$4\r\nname\r\n$3\r\nage\r\n")
+	})
+
+	client, err := Connect(context.Background(), server.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+
+	fields, err := client.HRandField(context.Background(), "user", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"name", "age"}, fields)
+	assert.Equal(t, [][]string{{"HRANDFIELD", "user", "2"}}, server.Commands())
+}
@@ -0,0 +1,109 @@
+package redis_client
+
+import (
+	"context"
+)
+
+// ListDirection is the end of a list elements are taken from or added to.
+type ListDirection string
+
+const (
+	ListLeft  ListDirection = "LEFT"
+	ListRight ListDirection = "RIGHT"
+)
+
+// InsertPosition says if LInsert adds the element before or after the pivot.
+type InsertPosition string
+
+const (
+	InsertBefore InsertPosition = "BEFORE"
+	InsertAfter  InsertPosition = "AFTER"
+)
+
+// LPosOptions are the options for LPOS, the zero value finds the first match scanning the whole list.
+type LPosOptions struct {
+	// Rank skips matches, 2 returns the second match, negative ranks search from the end of the list.
+	Rank int64
+	// MaxLen limits how many elements are compared, 0 compares all of them.
+	MaxLen int64
+}
+
+func (o LPosOptions) args(args []interface{}) []interface{} {
+	if o.Rank != 0 {
+		args = append(args, "RANK", o.Rank)
+	}
+
+	if o.MaxLen != 0 {
+		args = append(args, "MAXLEN", o.MaxLen)
+	}
+
+	return args
+}
+
+// LPush adds the values to the head of the list and returns the new length of the list.
+func (c *Commands) LPush(ctx context.Context, key string, values ...interface{}) (int64, error) {
+	return c.int64(ctx, "LPUSH", key, values)
+}
+
+// RPush adds the values to the tail of the list and returns the new length of the list.
+func (c *Commands) RPush(ctx context.Context, key string, values ...interface{}) (int64, error) {
+	return c.int64(ctx, "RPUSH", key, values)
+}
+
+// LPop removes and returns the first element of the list, it returns ErrNil if the list is empty.
+func (c *Commands) LPop(ctx context.Context, key string) (string, error) {
+	return c.string(ctx, "LPOP", key)
+}
+
+// LPopCount removes and returns up to count elements from the head of the list, it returns ErrNil if
+// the list is empty.
+func (c *Commands) LPopCount(ctx context.Context, key string, count int64) ([]string, error) {
+	return c.strings(ctx, "LPOP", key, count)
+}
+
+// RPop removes and returns the last element of the list, it returns ErrNil if the list is empty.
+func (c *Commands) RPop(ctx context.Context, key string) (string, error) {
+	return c.string(ctx, "RPOP", key)
+}
+
+// RPopCount removes and returns up to count elements from the tail of the list, it returns ErrNil if
+// the list is empty.
+func (c *Commands) RPopCount(ctx context.Context, key string, count int64) ([]string, error) {
+	return c.strings(ctx, "RPOP", key, count)
+}
+
+// LRange returns the elements from start to stop, both inclusive, negative offsets start from the end.
+func (c *Commands) LRange(ctx context.Context, key string, start int64, stop int64) ([]string, error) {
+	return c.strings(ctx, "LRANGE", key, start, stop)
+}
+
+// LIndex returns the element at index, it returns ErrNil if the index is out of range.
+func (c *Commands) LIndex(ctx context.Context, key string, index int64) (string, error) {
+	return c.string(ctx, "LINDEX", key, index)
+}
+
+// LInsert adds value before or after pivot and returns the new length of the list, or -1 if pivot was not found.
+func (c *Commands) LInsert(ctx context.Context, key string, position InsertPosition, pivot interface{}, value interface{}) (int64, error) {
+	return c.int64(ctx, "LINSERT", key, string(position), pivot, value)
+}
+
+// LMove removes an element from one end of source, adds it to one end of destination and returns it. it
+// returns ErrNil if source is empty.
+func (c *Commands) LMove(ctx context.Context, source string, destination string, from ListDirection, to ListDirection) (string, error) {
+	return c.string(ctx, "LMOVE", source, destination, string(from), string(to))
+}
+
+// LPos returns the index of element on the list, it returns ErrNil if there is no match.
+func (c *Commands) LPos(ctx context.Context, key string, element interface{}, options LPosOptions) (int64, error) {
+	return c.int64(ctx, options.args([]interface{}{"LPOS", key, element})...)
+}
+
+// LPosCount returns the indexes of up to count matches of element, a count of 0 returns all matches.
+func (c *Commands) LPosCount(ctx context.Context, key string, element interface{}, count int64, options LPosOptions) ([]int64, error) {
+	return c.int64s(ctx, options.args([]interface{}{"LPOS", key, element, "COUNT", count})...)
+}
+
+// LTrim keeps only the elements from start to stop, both inclusive.
+func (c *Commands) LTrim(ctx context.Context, key string, start int64, stop int64) error {
+	return c.status(ctx, "LTRIM", key, start, stop)
+}
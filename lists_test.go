@@ -0,0 +1,116 @@
+package redis_client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+func TestCommands_Lists(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	length, err := client.RPush(ctx, "list", "b", "c", 4)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), length)
+
+	length, err = client.LPush(ctx, "list", "a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), length)
+
+	values, err := client.LRange(ctx, "list", 0, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "4"}, values)
+
+	value, err := client.LIndex(ctx, "list", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "b", value)
+
+	_, err = client.LIndex(ctx, "list", 10)
+	assert.Equal(t, ErrNil, err)
+
+	length, err = client.LInsert(ctx, "list", InsertBefore, "c", "b2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), length)
+
+	length, err = client.LInsert(ctx, "list", InsertAfter, "missing", "x")
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), length)
+
+	value, err = client.LPop(ctx, "list")
+	require.NoError(t, err)
+	assert.Equal(t, "a", value)
+
+	value, err = client.RPop(ctx, "list")
+	require.NoError(t, err)
+	assert.Equal(t, "4", value)
+
+	require.NoError(t, client.LTrim(ctx, "list", 0, 1))
+
+	values, err = client.LRange(ctx, "list", 0, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "b2"}, values)
+
+	_, err = client.LPop(ctx, "missing")
+	assert.Equal(t, ErrNil, err)
+}
+
+func TestCommands_ListsWithCount(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.RPush(ctx, "list", "a", "b", "c", "d")
+	require.NoError(t, err)
+
+	values, err := client.LPopCount(ctx, "list", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, values)
+
+	values, err = client.RPopCount(ctx, "list", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d", "c"}, values)
+
+	_, err = client.LPopCount(ctx, "list", 2)
+	assert.Equal(t, ErrNil, err)
+}
+
+func TestCommands_ListArguments(t *testing.T) {
+	server := newFakeServer(t, func(w io.Writer, args []string) {
+		switch args[0] {
+		case "LMOVE":
+			io.WriteString(w, "$1\r\na\r\n")
+		case "LPOS":
+			if len(args) > 3 && args[3] == "COUNT" {
+				io.WriteString(w, "*2\r\n:0\r\n:3\r\n")
+			} else {
+				io.WriteString(w, ":3\r\n")
+			}
+		}
+	})
+
+	client, err := Connect(context.Background(), server.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx := context.Background()
+
+	value, err := client.LMove(ctx, "source", "destination", ListLeft, ListRight)
+	require.NoError(t, err)
+	assert.Equal(t, "a", value)
+
+	index, err := client.LPos(ctx, "list", "a", LPosOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), index)
+
+	indexes, err := client.LPosCount(ctx, "list", "a", 0, LPosOptions{Rank: -1, MaxLen: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{0, 3}, indexes)
+
+	assert.Equal(t, [][]string{
+		{"LMOVE", "source", "destination", "LEFT", "RIGHT"},
+		{"LPOS", "list", "a"},
+		{"LPOS", "list", "a", "COUNT", "0", "RANK", "-1", "MAXLEN", "10"},
+	}, server.Commands())
+}
@@ -0,0 +1,374 @@
+package redis_client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxMuxBatch is the max number of commands written on a single flush
+	maxMuxBatch = 1024
+	// maxMuxPending is how many commands can be waiting for a reply before writes wait for the reader
+	maxMuxPending = 4096
+)
+
+var (
+	_ io.Closer = &Multiplexer{}
+
+	// ErrMultiplexerClosed is returned when using a closed Multiplexer.
+	ErrMultiplexerClosed = errors.New("redis: multiplexer is closed")
+	// ErrUnsupportedCommand is returned by a Multiplexer for commands that change the connection state, like
+	// MULTI or SELECT, or that take over the connection, like SUBSCRIBE. these need a connection of their own.
+	ErrUnsupportedCommand = errors.New("redis: command can't be used on a shared connection")
+
+	unsupportedMuxCommands = map[string]bool{
+		"AUTH":         true,
+		"HELLO":        true,
+		"SELECT":       true,
+		"QUIT":         true,
+		"RESET":        true,
+		"MULTI":        true,
+		"EXEC":         true,
+		"DISCARD":      true,
+		"WATCH":        true,
+		"UNWATCH":      true,
+		"SUBSCRIBE":    true,
+		"PSUBSCRIBE":   true,
+		"SSUBSCRIBE":   true,
+		"UNSUBSCRIBE":  true,
+		"PUNSUBSCRIBE": true,
+		"SUNSUBSCRIBE": true,
+		"MONITOR":      true,
+	}
+
+	unsupportedMuxClientCommands = map[string]bool{
+		"REPLY":    true,
+		"SETNAME":  true,
+		"TRACKING": true,
+		"CACHING":  true,
+	}
+)
+
+// Multiplexer lets many goroutines share a single connection. commands from all goroutines are coalesced into
+// batched writes and their replies are matched in order, which gives pipelining throughput without having
+// to build pipelines by hand or keep a big pool of connections.
+//
+// blocking commands (BLPOP, XREAD BLOCK and friends) would hold every other command behind them, so they are
+// sent on dedicated connections from a pool instead. commands that change the state of the connection or take
+// it over (MULTI, WATCH, SELECT, SUBSCRIBE, ...) fail with ErrUnsupportedCommand.
+//
+// cancelling the context of a command stops the wait but doesn't break the connection, the reply is
+// discarded once it arrives. if the connection fails all commands waiting for replies fail with the error
+// and the next command opens a new connection.
+type Multiplexer struct {
+	Commands
+	options  Options
+	blocking *Pool
+
+	mutex  sync.Mutex
+	conn   *muxConn
+	closed bool
+}
+
+// NewMultiplexer connects to the server and returns a Multiplexer using this connection.
+func NewMultiplexer(ctx context.Context, options Options) (*Multiplexer, error) {
+	m := &Multiplexer{
+		options: options,
+		blocking: NewPool(PoolOptions{
+			Options: options,
+		}),
+	}
+	m.Commands = Commands{do: m.Do}
+
+	if _, err := m.connection(ctx); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Do sends the command on the shared connection and waits for its reply.
+func (m *Multiplexer) Do(ctx context.Context, args ...interface{}) (*Result, error) {
+	if len(args) == 0 {
+		return nil, errors.New("no command given")
+	}
+
+	if err := checkMuxCommand(args); err != nil {
+		return nil, err
+	}
+
+	if _, isBlocking := blockingTimeout(args); isBlocking {
+		return m.blocking.Do(ctx, args...)
+	}
+
+	payload, err := encodeCommand(args)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to execute operation: %v", args[0])
+	}
+
+	conn, err := m.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &muxRequest{
+		payload: payload,
+		done:    make(chan struct{}),
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.options.writeTimeout()+m.options.readTimeout())
+		defer cancel()
+	}
+
+	if err := conn.send(ctx, request); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-request.done:
+		return request.result, request.err
+	case <-ctx.Done():
+		return nil, errors.Wrapf(ctx.Err(), "operation %v interrupted", args[0])
+	}
+}
+
+// Close closes the shared connection and all connections used for blocking commands.
+func (m *Multiplexer) Close() error {
+	m.mutex.Lock()
+	conn := m.conn
+	m.closed = true
+	m.mutex.Unlock()
+
+	if conn != nil {
+		conn.fail(ErrMultiplexerClosed)
+	}
+
+	return m.blocking.Close()
+}
+
+// connection returns the current connection or opens a new one if the current one has failed.
+func (m *Multiplexer) connection(ctx context.Context) (*muxConn, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closed {
+		return nil, ErrMultiplexerClosed
+	}
+
+	if m.conn != nil && !m.conn.failed() {
+		return m.conn, nil
+	}
+
+	client, err := ConnectWithOptions(ctx, m.options)
+	if err != nil {
+		return nil, err
+	}
+
+	m.conn = newMuxConn(client)
+
+	return m.conn, nil
+}
+
+func checkMuxCommand(args []interface{}) error {
+	command := strings.ToUpper(fmt.Sprint(args[0]))
+	if unsupportedMuxCommands[command] {
+		return errors.Wrapf(ErrUnsupportedCommand, "%v failed", command)
+	}
+
+	if command == "CLIENT" && len(args) > 1 {
+		subcommand := strings.ToUpper(fmt.Sprint(args[1]))
+		if unsupportedMuxClientCommands[subcommand] {
+			return errors.Wrapf(ErrUnsupportedCommand, "%v %v failed", command, subcommand)
+		}
+	}
+
+	return nil
+}
+
+func encodeCommand(args []interface{}) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	writer := NewWriter(buffer)
+	if err := writer.WriteCommand(args...); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Flush(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+type muxRequest struct {
+	payload []byte
+	result  *Result
+	err     error
+	done    chan struct{}
+}
+
+func (r *muxRequest) finish(result *Result, err error) {
+	r.result = result
+	r.err = err
+	close(r.done)
+}
+
+// muxConn is a single connection with a goroutine writing the commands and another one reading the replies.
+// commands are queued on pending before they are written so the reader always knows who the next reply is for.
+type muxConn struct {
+	client   *Client
+	requests chan *muxRequest
+	pending  chan *muxRequest
+	done     chan struct{}
+	once     sync.Once
+	err      error
+	loops    sync.WaitGroup
+}
+
+func newMuxConn(client *Client) *muxConn {
+	c := &muxConn{
+		client:   client,
+		requests: make(chan *muxRequest),
+		pending:  make(chan *muxRequest, maxMuxPending),
+		done:     make(chan struct{}),
+	}
+
+	// the connection has no deadlines until there is a reply to wait for
+	client.conn.SetDeadline(time.Time{})
+
+	c.loops.Add(2)
+	go c.writeLoop()
+	go c.readLoop()
+
+	go func() {
+		// once both loops are gone nobody else touches pending, so whatever is left there never gets a reply
+		c.loops.Wait()
+		close(c.pending)
+		for request := range c.pending {
+			request.finish(nil, c.err)
+		}
+	}()
+
+	return c
+}
+
+func (c *muxConn) send(ctx context.Context, request *muxRequest) error {
+	select {
+	case c.requests <- request:
+		return nil
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *muxConn) failed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// fail closes the connection, only the first error is kept.
+func (c *muxConn) fail(err error) {
+	c.once.Do(func() {
+		c.err = err
+		close(c.done)
+		c.client.Close()
+	})
+}
+
+func (c *muxConn) writeLoop() {
+	defer c.loops.Done()
+
+	batch := make([]*muxRequest, 0, maxMuxBatch)
+
+	for {
+		select {
+		case request := <-c.requests:
+			batch = append(batch[:0], request)
+		case <-c.done:
+			return
+		}
+
+		// take everyone that is waiting to write right now so they all go on the same flush
+	collect:
+		for len(batch) < maxMuxBatch {
+			select {
+			case request := <-c.requests:
+				batch = append(batch, request)
+			default:
+				break collect
+			}
+		}
+
+		c.client.conn.SetWriteDeadline(time.Now().Add(c.client.options.writeTimeout()))
+
+		for x, request := range batch {
+			select {
+			case c.pending <- request:
+			case <-c.done:
+				for _, r := range batch[x:] {
+					r.finish(nil, c.err)
+				}
+				return
+			}
+
+			// payloads are not changed once encoded so large ones can go out without being copied
+			c.client.writer.writeRaw(request.payload)
+		}
+
+		if err := c.client.writer.Flush(); err != nil {
+			c.fail(errors.Wrap(err, "failed to write commands"))
+			return
+		}
+	}
+}
+
+func (c *muxConn) readLoop() {
+	defer c.loops.Done()
+
+	for {
+		var request *muxRequest
+		select {
+		case request = <-c.pending:
+		case <-c.done:
+			return
+		}
+
+		c.client.conn.SetReadDeadline(time.Now().Add(c.client.options.readTimeout()))
+
+		result, err := c.read()
+		if err != nil {
+			c.fail(errors.Wrap(err, "failed to read reply"))
+			request.finish(nil, c.err)
+			return
+		}
+
+		request.finish(result, nil)
+	}
+}
+
+// read reads the next reply, skipping out of band RESP3 push frames.
+func (c *muxConn) read() (*Result, error) {
+	for {
+		result, err := c.client.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, isPush := result.content.(Push); !isPush {
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package redis_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMultiplexer_Do(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	m, err := NewMultiplexer(context.Background(), Options{Address: server.Addr()})
+	require.NoError(t, err)
+	defer m.Close()
+
+	wg := sync.WaitGroup{}
+	for x := 0; x < 50; x++ {
+		wg.Add(1)
+		go func(x int) {
+			defer wg.Done()
+
+			for y := 0; y < 20; y++ {
+				key := fmt.Sprintf("key-%v-%v", x, y)
+				result, err := m.Do(context.Background(), "SET", key, key)
+				if assert.NoError(t, err) {
+					assert.Equal(t, "OK", result.Content())
+				}
+
+				result, err = m.Do(context.Background(), "GET", key)
+				if assert.NoError(t, err) {
+					assert.Equal(t, key, result.Content())
+				}
+
+				_, err = m.Do(context.Background(), "INCR", "counter")
+				assert.NoError(t, err)
+			}
+		}(x)
+	}
+	wg.Wait()
+
+	value, err := server.Get("counter")
+	require.NoError(t, err)
+	assert.Equal(t, "1000", value)
+
+	// all commands went through a single connection
+	assert.Equal(t, 1, server.TotalConnectionCount())
+}
+
+func TestMultiplexer_UnsupportedCommands(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	m, err := NewMultiplexer(context.Background(), Options{Address: server.Addr()})
+	require.NoError(t, err)
+	defer m.Close()
+
+	for _, args := range [][]interface{}{
+		{"MULTI"},
+		{"subscribe", "some-channel"},
+		{"SELECT", 1},
+		{"CLIENT", "REPLY", "OFF"},
+	} {
+		_, err := m.Do(context.Background(), args...)
+		assert.True(t, errors.Is(err, ErrUnsupportedCommand), "%v should not be supported but got %v", args, err)
+	}
+
+	_, err = m.Do(context.Background(), "SET", "some-key", struct{}{})
+	assert.Error(t, err)
+
+	// invalid commands don't break the connection
+	result, err := m.Do(context.Background(), "PING")
+	require.NoError(t, err)
+	assert.Equal(t, "PONG", result.Content())
+}
+
+func TestMultiplexer_BlockingCommands(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	m, err := NewMultiplexer(context.Background(), Options{Address: server.Addr()})
+	require.NoError(t, err)
+	defer m.Close()
+
+	done := make(chan *Result)
+	go func() {
+		result, err := m.Do(context.Background(), "BLPOP", "some-list", 5)
+		assert.NoError(t, err)
+		done <- result
+	}()
+
+	// other commands keep flowing while BLPOP waits on its own connection
+	time.Sleep(time.Millisecond * 50)
+	result, err := m.Do(context.Background(), "RPUSH", "some-list", "value")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Content())
+
+	select {
+	case result := <-done:
+		assert.Equal(t, []interface{}{"some-list", "value"}, result.Content())
+	case <-time.After(time.Second):
+		t.Fatal("BLPOP did not return")
+	}
+
+	assert.Equal(t, 2, server.TotalConnectionCount())
+}
+
+func TestMultiplexer_Cancelled(t *testing.T) {
+	server := newFakeServer(t, func(w io.Writer, args []string) {
+		if args[0] == "SLOW" {
+			time.Sleep(time.Millisecond * 100)
+		}
+
+		io.WriteString(w, "+"+args[0]+"\r\n")
+	})
+
+	m, err := NewMultiplexer(context.Background(), Options{Address: server.Addr()})
+	require.NoError(t, err)
+	defer m.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+
+	_, err = m.Do(ctx, "SLOW")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// the reply for SLOW is discarded and the next command gets its own reply
+	result, err := m.Do(context.Background(), "FAST")
+	require.NoError(t, err)
+	assert.Equal(t, "FAST", result.Content())
+}
+
+func TestMultiplexer_Reconnects(t *testing.T) {
+	server := newFakeServer(t, func(w io.Writer, args []string) {
+		if args[0] == "BROKEN" {
+			io.WriteString(w, "?broken\r\n")
+			return
+		}
+
+		io.WriteString(w, "+"+args[0]+"\r\n")
+	})
+
+	m, err := NewMultiplexer(context.Background(), Options{Address: server.Addr()})
+	require.NoError(t, err)
+	defer m.Close()
+
+	_, err = m.Do(context.Background(), "BROKEN")
+	assert.EqualError(t, err, "failed to read reply: unknown RESP type '?', actual content in base64: [P2Jyb2tlbg]")
+
+	result, err := m.Do(context.Background(), "PING")
+	require.NoError(t, err)
+	assert.Equal(t, "PING", result.Content())
+}
+
+func TestMultiplexer_Close(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	m, err := NewMultiplexer(context.Background(), Options{Address: server.Addr()})
+	require.NoError(t, err)
+	defer m.Close()
+	require.NoError(t, m.Close())
+
+	_, err = m.Do(context.Background(), "PING")
+	assert.Equal(t, ErrMultiplexerClosed, err)
+}
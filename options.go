@@ -0,0 +1,228 @@
+package redis_client
+
+import (
+	"crypto/tls"
+	"fmt"
+	"github.com/pkg/errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPort        = "6379"
+	defaultDialTimeout = time.Second * 5
+	defaultKeepAlive   = time.Second * 10
+)
+
+// Options configures how a client connects to and introduces itself to the server.
+type Options struct {
+	// Network is either tcp, tcp4, tcp6 or unix, defaults to tcp.
+	Network string
+	// Address is the host:port of the server or the path to the socket file for unix sockets.
+	Address string
+	// DialTimeout is how long to wait for the connection to be established, defaults to 5 seconds.
+	DialTimeout time.Duration
+	// ReadTimeout is how long to wait for a reply, defaults to 5 seconds. blocking commands
+	// wait for this plus the time they block for.
+	ReadTimeout time.Duration
+	// WriteTimeout is how long to wait for a command to be written, defaults to ReadTimeout.
+	WriteTimeout time.Duration
+	// Protocol is the RESP version to negotiate. 3 sends `HELLO 3` and switches the connection to RESP3,
+	// falling back to RESP2 if the server rejects it. any other value keeps the connection on RESP2.
+	Protocol int
+	// Username and Password are sent with `HELLO` or `AUTH`, an empty Username means the default user.
+	Username string
+	Password string
+	// Database is selected with `SELECT` once connected if it isn't 0.
+	Database int
+	// ClientName is set as the connection name with `HELLO` or `CLIENT SETNAME`.
+	ClientName string
+	// TLSConfig makes the connection use TLS when set. client certificates for mutual TLS go in
+	// TLSConfig.Certificates and if TLSConfig.ServerName is empty the host from Address is used for SNI
+	// and to verify the server certificate.
+	TLSConfig *tls.Config
+	// MaxBulkLength is the largest bulk string, in bytes, accepted in a reply, defaults to 512MB like the
+	// `proto-max-bulk-len` default on the server. replies over it fail and break the connection.
+	MaxBulkLength int64
+}
+
+func (o Options) network() string {
+	if o.Network == "" {
+		return "tcp"
+	}
+
+	return o.Network
+}
+
+func (o Options) tlsConfig() *tls.Config {
+	config := o.TLSConfig.Clone()
+	if config.ServerName == "" && o.network() != "unix" {
+		if host, _, err := net.SplitHostPort(o.Address); err == nil {
+			config.ServerName = host
+		}
+	}
+
+	return config
+}
+
+func (o Options) maxBulkLength() int64 {
+	if o.MaxBulkLength == 0 {
+		return defaultMaxBulkLength
+	}
+
+	return o.MaxBulkLength
+}
+
+func (o Options) dialTimeout() time.Duration {
+	if o.DialTimeout == 0 {
+		return defaultDialTimeout
+	}
+
+	return o.DialTimeout
+}
+
+func (o Options) readTimeout() time.Duration {
+	if o.ReadTimeout == 0 {
+		return defaultTimeout
+	}
+
+	return o.ReadTimeout
+}
+
+func (o Options) writeTimeout() time.Duration {
+	if o.WriteTimeout == 0 {
+		return o.readTimeout()
+	}
+
+	return o.WriteTimeout
+}
+
+// withAddress returns a copy of the options for a server discovered at runtime, like a cluster node. the
+// server name from TLSConfig was meant for the original address, so it is cleared to use the new host.
+func (o Options) withAddress(address string) Options {
+	o.Address = address
+
+	if o.TLSConfig != nil {
+		o.TLSConfig = o.TLSConfig.Clone()
+		o.TLSConfig.ServerName = ""
+	}
+
+	return o
+}
+
+// ParseURL reads connection options from a URL so they can come from a single configuration value.
+// supported formats are:
+//
+//	redis://[[username][:password]@][host][:port][/database][?option=value]
+//	rediss://[[username][:password]@][host][:port][/database][?option=value]
+//	unix://[[username][:password]@]/path/to/redis.sock[?option=value]
+//
+// rediss connects with TLS, verifying the server certificate against the system roots. the database can also
+// be set with the `db` option. other options are `protocol`, `client_name`, `dial_timeout`, `read_timeout`,
+// `write_timeout` and `tls_server_name` (rediss only, overrides the name used for SNI and verification).
+// timeouts are either Go durations (`500ms`) or a number of seconds.
+func ParseURL(rawURL string) (Options, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Options{}, errors.Wrap(err, "invalid redis URL")
+	}
+
+	options := Options{}
+
+	if u.User != nil {
+		options.Username = u.User.Username()
+		options.Password, _ = u.User.Password()
+	}
+
+	switch u.Scheme {
+	case "redis", "rediss":
+		host, port, err := net.SplitHostPort(u.Host)
+		if err != nil {
+			// there was no port on the address
+			host, port = u.Host, defaultPort
+		}
+
+		if host == "" {
+			host = "localhost"
+		}
+
+		options.Network = "tcp"
+		options.Address = net.JoinHostPort(strings.Trim(host, "[]"), port)
+
+		if path := strings.Trim(u.Path, "/"); path != "" {
+			options.Database, err = strconv.Atoi(path)
+			if err != nil {
+				return Options{}, fmt.Errorf("invalid database %q on redis URL", path)
+			}
+		}
+
+		if u.Scheme == "rediss" {
+			options.TLSConfig = &tls.Config{
+				ServerName: strings.Trim(host, "[]"),
+				MinVersion: tls.VersionTLS12,
+			}
+		}
+	case "unix":
+		if u.Path == "" {
+			return Options{}, fmt.Errorf("unix socket URL has no path: %v", rawURL)
+		}
+
+		options.Network = "unix"
+		options.Address = u.Path
+	default:
+		return Options{}, fmt.Errorf("invalid redis URL scheme %q, it must be redis, rediss or unix", u.Scheme)
+	}
+
+	if err := parseQuery(u.Query(), &options); err != nil {
+		return Options{}, err
+	}
+
+	return options, nil
+}
+
+func parseQuery(query url.Values, options *Options) error {
+	for key, values := range query {
+		value := values[len(values)-1]
+
+		var err error
+		switch key {
+		case "db":
+			options.Database, err = strconv.Atoi(value)
+		case "protocol":
+			options.Protocol, err = strconv.Atoi(value)
+		case "client_name":
+			options.ClientName = value
+		case "dial_timeout":
+			options.DialTimeout, err = parseDuration(value)
+		case "read_timeout":
+			options.ReadTimeout, err = parseDuration(value)
+		case "write_timeout":
+			options.WriteTimeout, err = parseDuration(value)
+		case "tls_server_name":
+			if options.TLSConfig == nil {
+				return fmt.Errorf("option %q is only valid for rediss URLs", key)
+			}
+			options.TLSConfig.ServerName = value
+		default:
+			return fmt.Errorf("unknown option %q on redis URL", key)
+		}
+
+		if err != nil {
+			return fmt.Errorf("invalid value %q for option %q on redis URL", value, key)
+		}
+	}
+
+	return nil
+}
+
+// parseDuration accepts Go durations like `1m30s` or a plain number of seconds.
+func parseDuration(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,101 @@
+package redis_client
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// Pipeline queues commands and sends them all in a single write, reading all replies after that, so N
+// commands cost a single round trip instead of N. a Pipeline is not safe to be used by many goroutines.
+type Pipeline struct {
+	exec     func(ctx context.Context, commands [][]interface{}) ([]*Result, error)
+	commands [][]interface{}
+}
+
+// Pipeline creates a pipeline that executes its commands on this connection.
+func (c *Client) Pipeline() *Pipeline {
+	return &Pipeline{
+		exec: c.pipeline,
+	}
+}
+
+// Pipeline creates a pipeline that borrows a single connection from the pool to execute its commands.
+func (p *Pool) Pipeline() *Pipeline {
+	return &Pipeline{
+		exec: p.pipeline,
+	}
+}
+
+// Queue adds a command to the pipeline, nothing is sent until Exec is called.
+func (p *Pipeline) Queue(args ...interface{}) {
+	p.commands = append(p.commands, args)
+}
+
+// Len returns how many commands are queued.
+func (p *Pipeline) Len() int {
+	return len(p.commands)
+}
+
+// Exec sends all queued commands and returns their results in the same order they were queued, the pipeline
+// is empty after that and can be reused. errors returned by the server are on each Result. if the connection
+// fails halfway the error is returned and every command whose reply could not be read gets a Result
+// with this error, these commands might or might not have been executed by the server.
+func (p *Pipeline) Exec(ctx context.Context) ([]*Result, error) {
+	commands := p.commands
+	p.commands = nil
+
+	if len(commands) == 0 {
+		return nil, nil
+	}
+
+	for x, args := range commands {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("command %v on pipeline is empty", x)
+		}
+	}
+
+	return p.exec(ctx, commands)
+}
+
+func (c *Client) pipeline(ctx context.Context, commands [][]interface{}) ([]*Result, error) {
+	results := make([]*Result, 0, len(commands))
+
+	err := c.executeAll(ctx, commands, func() error {
+		for range commands {
+			result, err := c.reader.Read()
+			if err != nil {
+				return err
+			}
+
+			results = append(results, result)
+		}
+
+		return nil
+	})
+
+	return failRemaining(results, commands, err), err
+}
+
+func (p *Pool) pipeline(ctx context.Context, commands [][]interface{}) ([]*Result, error) {
+	client, err := p.Acquire(ctx)
+	if err != nil {
+		return failRemaining(nil, commands, err), err
+	}
+	defer p.Release(client)
+
+	return client.pipeline(ctx, commands)
+}
+
+// failRemaining fills the results for the commands that did not get a reply with the error that stopped them.
+// once a reply fails to be read there is no way to know where the next reply starts, so we can't read
+// anything else from this connection.
+func failRemaining(results []*Result, commands [][]interface{}, err error) []*Result {
+	for x := len(results); x < len(commands); x++ {
+		results = append(results, &Result{
+			content: errors.Wrapf(err, "no reply for command %v (%v) on pipeline", x, commands[x][0]),
+		})
+	}
+
+	return results
+}
@@ -0,0 +1,120 @@
+package redis_client
+
+import (
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+func TestPipeline_Exec(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	client, err := Connect(context.Background(), server.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+
+	pipeline := client.Pipeline()
+	pipeline.Queue("SET", "some-key", "some-value")
+	pipeline.Queue("LPUSH", "some-key", "value")
+	pipeline.Queue("INCR", "counter")
+	pipeline.Queue("GET", "some-key")
+	assert.Equal(t, 4, pipeline.Len())
+
+	results, err := pipeline.Exec(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, pipeline.Len())
+
+	require.Len(t, results, 4)
+	assert.Equal(t, "OK", results[0].Content())
+	assert.EqualError(t, results[1].Err(), "WRONGTYPE Operation against a key holding the wrong kind of value")
+	assert.Equal(t, int64(1), results[2].Content())
+	assert.Equal(t, "some-value", results[3].Content())
+
+	// the connection is still good after the pipeline
+	result, err := client.Do(context.Background(), "GET", "counter")
+	require.NoError(t, err)
+	assert.Equal(t, "1", result.Content())
+}
+
+func TestPipeline_ExecEmpty(t *testing.T) {
+	pipeline := (&Client{}).Pipeline()
+
+	results, err := pipeline.Exec(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	pipeline.Queue()
+	_, err = pipeline.Exec(context.Background())
+	assert.EqualError(t, err, "command 0 on pipeline is empty")
+}
+
+func TestPipeline_ExecProtocolFailure(t *testing.T) {
+	server := newFakeServer(t, func(w io.Writer, args []string) {
+		switch args[0] {
+		case "BROKEN":
+			io.WriteString(w, "?this is not RESP\r\n")
+		default:
+			io.WriteString(w, "+OK\r\n")
+		}
+	})
+
+	client, err := Connect(context.Background(), server.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+
+	pipeline := client.Pipeline()
+	pipeline.Queue("SET", "a", "1")
+	pipeline.Queue("BROKEN")
+	pipeline.Queue("SET", "b", "2")
+
+	results, err := pipeline.Exec(context.Background())
+	assert.EqualError(t, err, "unknown RESP type '?', actual content in base64: [P3RoaXMgaXMgbm90IFJFU1A]")
+	require.Len(t, results, 3)
+
+	assert.Equal(t, "OK", results[0].Content())
+	assert.EqualError(t, results[1].Err(), "no reply for command 1 (BROKEN) on pipeline: unknown RESP type '?', actual content in base64: [P3RoaXMgaXMgbm90IFJFU1A]")
+	assert.EqualError(t, results[2].Err(), "no reply for command 2 (SET) on pipeline: unknown RESP type '?', actual content in base64: [P3RoaXMgaXMgbm90IFJFU1A]")
+	assert.True(t, client.Broken())
+}
+
+func TestPool_Pipeline(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	pool := NewPool(PoolOptions{
+		Options:   Options{Address: server.Addr()},
+		MaxActive: 1,
+	})
+	defer pool.Close()
+
+	pipeline := pool.Pipeline()
+	for x := 0; x < 100; x++ {
+		pipeline.Queue("INCR", "counter")
+	}
+
+	results, err := pipeline.Exec(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 100)
+
+	for x, result := range results {
+		assert.Equal(t, int64(x+1), result.Content())
+	}
+
+	client, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+
+	pipeline.Queue("PING")
+	results, err = pipeline.Exec(context.Background())
+	assert.True(t, errors.Is(err, ErrPoolExhausted))
+	require.Len(t, results, 1)
+	assert.True(t, errors.Is(results[0].Err(), ErrPoolExhausted))
+
+	pool.Release(client)
+}
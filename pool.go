@@ -0,0 +1,364 @@
+package redis_client
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultMaxIdle = 10
+)
+
+var (
+	_ io.Closer = &Pool{}
+
+	// ErrPoolExhausted is returned when all connections are in use and the pool isn't configured to wait.
+	ErrPoolExhausted = errors.New("redis: connection pool exhausted")
+	// ErrPoolClosed is returned when getting connections from a closed pool.
+	ErrPoolClosed = errors.New("redis: connection pool is closed")
+)
+
+// PoolOptions configures a Pool.
+type PoolOptions struct {
+	// Options is how connections are created.
+	Options Options
+	// Dial overrides how connections are created, by default it calls ConnectWithOptions with Options.
+	Dial func(ctx context.Context) (*Client, error)
+	// MaxActive is the max number of connections open at the same time, both in use and idle.
+	// zero means there is no limit.
+	MaxActive int
+	// MaxIdle is the max number of idle connections kept open, defaults to 10.
+	MaxIdle int
+	// IdleTimeout closes connections that have been idle for longer than this, zero keeps them open.
+	IdleTimeout time.Duration
+	// MaxConnLifetime closes connections older than this, zero keeps them open.
+	MaxConnLifetime time.Duration
+	// Wait makes Acquire wait for a connection to be returned when MaxActive connections are in use,
+	// otherwise Acquire fails right away with ErrPoolExhausted.
+	Wait bool
+	// WaitTimeout is how long to wait for a connection when Wait is set, zero means only the context
+	// can stop the wait.
+	WaitTimeout time.Duration
+	// HealthCheckInterval makes Acquire send a PING on connections that have been idle for longer than this
+	// before handing them out, connections that fail the check are closed. zero disables the check.
+	HealthCheckInterval time.Duration
+}
+
+// PoolStats are counters and gauges to see how the pool is behaving.
+type PoolStats struct {
+	// Hits is how many times an idle connection was reused.
+	Hits uint64
+	// Misses is how many times a new connection had to be created.
+	Misses uint64
+	// Timeouts is how many times Acquire gave up waiting for a connection.
+	Timeouts uint64
+	// StaleConns is how many idle connections were closed for being idle, too old or failing the health check.
+	StaleConns uint64
+	// TotalConns is how many connections are open right now, both in use and idle.
+	TotalConns int
+	// IdleConns is how many idle connections are open right now.
+	IdleConns int
+}
+
+// Pool hands out connections so a single Pool can be shared by many goroutines, each connection
+// is only used by one goroutine at a time. connections borrowed with Acquire must be returned with Release,
+// typed commands borrow and return a connection by themselves.
+type Pool struct {
+	Commands
+	options PoolOptions
+	// tokens limits how many connections can be open, it is nil if there is no limit
+	tokens chan struct{}
+
+	mutex  sync.Mutex
+	idle   []*Client
+	total  int
+	closed bool
+
+	hits       uint64
+	misses     uint64
+	timeouts   uint64
+	staleConns uint64
+}
+
+func NewPool(options PoolOptions) *Pool {
+	if options.MaxIdle == 0 {
+		options.MaxIdle = defaultMaxIdle
+	}
+
+	if options.Dial == nil {
+		connectOptions := options.Options
+		options.Dial = func(ctx context.Context) (*Client, error) {
+			return ConnectWithOptions(ctx, connectOptions)
+		}
+	}
+
+	p := &Pool{
+		options: options,
+	}
+	p.Commands = Commands{do: p.Do}
+
+	if options.MaxActive > 0 {
+		p.tokens = make(chan struct{}, options.MaxActive)
+	}
+
+	return p
+}
+
+// Acquire returns an idle connection or creates a new one. the connection must be returned with Release once done.
+func (p *Pool) Acquire(ctx context.Context) (*Client, error) {
+	// a done context would fail the health check of every idle connection and get them all closed
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := p.takeToken(ctx); err != nil {
+		return nil, err
+	}
+
+	for {
+		client, err := p.popIdle()
+		if err != nil {
+			p.returnToken()
+			return nil, err
+		}
+
+		if client == nil {
+			break
+		}
+
+		healthy, err := p.healthy(ctx, client)
+		if err != nil {
+			p.putBack(client)
+			p.returnToken()
+			return nil, err
+		}
+
+		if healthy {
+			atomic.AddUint64(&p.hits, 1)
+			return client, nil
+		}
+
+		atomic.AddUint64(&p.staleConns, 1)
+		p.closeConn(client)
+	}
+
+	atomic.AddUint64(&p.misses, 1)
+
+	client, err := p.options.Dial(ctx)
+	if err != nil {
+		p.returnToken()
+		return nil, err
+	}
+
+	client.createdAt = time.Now()
+
+	p.mutex.Lock()
+	p.total++
+	p.mutex.Unlock()
+
+	return client, nil
+}
+
+// Release returns a connection to the pool. broken connections and connections over the idle limits are closed.
+func (p *Pool) Release(client *Client) {
+	if client == nil {
+		return
+	}
+
+	defer p.returnToken()
+
+	now := time.Now()
+
+	p.mutex.Lock()
+	if !p.closed && !client.Broken() && len(p.idle) < p.options.MaxIdle && !p.expired(client, now) {
+		client.usedAt = now
+		p.idle = append(p.idle, client)
+		p.mutex.Unlock()
+		return
+	}
+	p.mutex.Unlock()
+
+	p.closeConn(client)
+}
+
+// Do borrows a connection, executes the command on it and returns it to the pool.
+func (p *Pool) Do(ctx context.Context, args ...interface{}) (*Result, error) {
+	client, err := p.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Release(client)
+
+	return client.Do(ctx, args...)
+}
+
+// DoBulkTo borrows a connection and calls DoBulkTo on it.
+func (p *Pool) DoBulkTo(ctx context.Context, w io.Writer, args ...interface{}) (int64, error) {
+	client, err := p.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer p.Release(client)
+
+	return client.DoBulkTo(ctx, w, args...)
+}
+
+// Stats returns the pool counters at this moment.
+func (p *Pool) Stats() PoolStats {
+	p.mutex.Lock()
+	total, idle := p.total, len(p.idle)
+	p.mutex.Unlock()
+
+	return PoolStats{
+		Hits:       atomic.LoadUint64(&p.hits),
+		Misses:     atomic.LoadUint64(&p.misses),
+		Timeouts:   atomic.LoadUint64(&p.timeouts),
+		StaleConns: atomic.LoadUint64(&p.staleConns),
+		TotalConns: total,
+		IdleConns:  idle,
+	}
+}
+
+// Close closes all idle connections, connections in use are closed once they are returned.
+func (p *Pool) Close() error {
+	p.mutex.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mutex.Unlock()
+
+	for _, client := range idle {
+		p.closeConn(client)
+	}
+
+	return nil
+}
+
+// takeToken takes one of the tokens that limit how many connections can be open.
+func (p *Pool) takeToken(ctx context.Context) error {
+	if p.tokens == nil {
+		return nil
+	}
+
+	select {
+	case p.tokens <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if !p.options.Wait {
+		return ErrPoolExhausted
+	}
+
+	if p.options.WaitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.options.WaitTimeout)
+		defer cancel()
+	}
+
+	select {
+	case p.tokens <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		atomic.AddUint64(&p.timeouts, 1)
+		return errors.Wrap(ctx.Err(), "timed out waiting for a connection")
+	}
+}
+
+func (p *Pool) returnToken() {
+	if p.tokens != nil {
+		<-p.tokens
+	}
+}
+
+// popIdle returns the most recently used idle connection, closing the ones that have expired on the way.
+func (p *Pool) popIdle() (*Client, error) {
+	now := time.Now()
+
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return nil, ErrPoolClosed
+	}
+
+	// idle connections are ordered by when they were last used, not by when they were created, so a
+	// connection over MaxConnLifetime can be anywhere on the list
+	var expired []*Client
+	kept := p.idle[:0]
+	for _, c := range p.idle {
+		if p.expired(c, now) {
+			expired = append(expired, c)
+		} else {
+			kept = append(kept, c)
+		}
+	}
+
+	for x := len(kept); x < len(p.idle); x++ {
+		p.idle[x] = nil
+	}
+	p.idle = kept
+
+	var client *Client
+	if len(p.idle) > 0 {
+		client = p.idle[len(p.idle)-1]
+		p.idle[len(p.idle)-1] = nil
+		p.idle = p.idle[:len(p.idle)-1]
+	}
+	p.mutex.Unlock()
+
+	for _, c := range expired {
+		atomic.AddUint64(&p.staleConns, 1)
+		p.closeConn(c)
+	}
+
+	return client, nil
+}
+
+func (p *Pool) expired(client *Client, now time.Time) bool {
+	if p.options.MaxConnLifetime > 0 && now.Sub(client.createdAt) >= p.options.MaxConnLifetime {
+		return true
+	}
+
+	return p.options.IdleTimeout > 0 && !client.usedAt.IsZero() && now.Sub(client.usedAt) >= p.options.IdleTimeout
+}
+
+// healthy sends a PING on connections that have been idle for too long. it returns the context error if the
+// context ended before the PING could tell if the connection is fine.
+func (p *Pool) healthy(ctx context.Context, client *Client) (bool, error) {
+	if p.options.HealthCheckInterval <= 0 || time.Since(client.usedAt) < p.options.HealthCheckInterval {
+		return true, nil
+	}
+
+	result, err := client.Do(ctx, "PING")
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		return false, ctxErr
+	}
+
+	return err == nil && result.Err() == nil, nil
+}
+
+// putBack returns a connection taken from the idle list without changing when it was last used, so it is
+// still checked the next time it is handed out. connections the PING left broken are closed.
+func (p *Pool) putBack(client *Client) {
+	p.mutex.Lock()
+	if !p.closed && !client.Broken() && len(p.idle) < p.options.MaxIdle {
+		p.idle = append(p.idle, client)
+		p.mutex.Unlock()
+		return
+	}
+	p.mutex.Unlock()
+
+	p.closeConn(client)
+}
+
+func (p *Pool) closeConn(client *Client) {
+	p.mutex.Lock()
+	p.total--
+	p.mutex.Unlock()
+
+	client.Close()
+}
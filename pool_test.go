@@ -0,0 +1,337 @@
+package redis_client
+
+import (
+	"context"
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPool_Do(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	pool := NewPool(PoolOptions{Options: Options{Address: server.Addr()}})
+	defer pool.Close()
+
+	for x := 0; x < 3; x++ {
+		result, err := pool.Do(context.Background(), "INCR", "counter")
+		require.NoError(t, err)
+		assert.Equal(t, int64(x+1), result.Content())
+	}
+
+	assert.Equal(t, PoolStats{
+		Hits:       2,
+		Misses:     1,
+		TotalConns: 1,
+		IdleConns:  1,
+	}, pool.Stats())
+}
+
+func TestPool_Concurrent(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	pool := NewPool(PoolOptions{
+		Options:   Options{Address: server.Addr()},
+		MaxActive: 5,
+		MaxIdle:   3,
+		Wait:      true,
+	})
+	defer pool.Close()
+
+	wg := sync.WaitGroup{}
+	for x := 0; x < 20; x++ {
+		wg.Add(1)
+		go func(x int) {
+			defer wg.Done()
+
+			for y := 0; y < 10; y++ {
+				_, err := pool.Do(context.Background(), "SET", fmt.Sprintf("key-%v-%v", x, y), "value")
+				assert.NoError(t, err)
+			}
+		}(x)
+	}
+	wg.Wait()
+
+	stats := pool.Stats()
+	assert.Equal(t, uint64(200), stats.Hits+stats.Misses)
+	assert.LessOrEqual(t, stats.TotalConns, 3)
+	assert.Equal(t, stats.TotalConns, stats.IdleConns)
+	assert.Len(t, server.Keys(), 200)
+}
+
+func TestPool_Exhausted(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	pool := NewPool(PoolOptions{
+		Options:   Options{Address: server.Addr()},
+		MaxActive: 1,
+	})
+	defer pool.Close()
+
+	client, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+
+	_, err = pool.Acquire(context.Background())
+	assert.Equal(t, ErrPoolExhausted, err)
+
+	pool.Release(client)
+
+	client, err = pool.Acquire(context.Background())
+	require.NoError(t, err)
+	pool.Release(client)
+}
+
+func TestPool_Wait(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	pool := NewPool(PoolOptions{
+		Options:     Options{Address: server.Addr()},
+		MaxActive:   1,
+		Wait:        true,
+		WaitTimeout: time.Millisecond * 50,
+	})
+	defer pool.Close()
+
+	client, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+
+	_, err = pool.Acquire(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, uint64(1), pool.Stats().Timeouts)
+
+	time.AfterFunc(time.Millisecond*10, func() {
+		pool.Release(client)
+	})
+
+	other, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+	assert.Same(t, client, other)
+	pool.Release(other)
+}
+
+func TestPool_ExpiredConnections(t *testing.T) {
+	tt := []struct {
+		name    string
+		options PoolOptions
+	}{
+		{
+			name: "idle timeout",
+			options: PoolOptions{
+				IdleTimeout: time.Millisecond * 20,
+			},
+		},
+		{
+			name: "max connection lifetime",
+			options: PoolOptions{
+				MaxConnLifetime: time.Millisecond * 20,
+			},
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			server, err := miniredis.Run()
+			require.NoError(t, err)
+			defer server.Close()
+
+			options := ts.options
+			options.Options.Address = server.Addr()
+			pool := NewPool(options)
+			defer pool.Close()
+
+			client, err := pool.Acquire(context.Background())
+			require.NoError(t, err)
+			pool.Release(client)
+
+			time.Sleep(time.Millisecond * 30)
+
+			other, err := pool.Acquire(context.Background())
+			require.NoError(t, err)
+			assert.NotSame(t, client, other)
+			pool.Release(other)
+
+			assert.Equal(t, PoolStats{
+				Misses:     2,
+				StaleConns: 1,
+				TotalConns: 1,
+				IdleConns:  1,
+			}, pool.Stats())
+		})
+	}
+}
+
+func TestPool_MaxConnLifetimeRecentlyUsed(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	pool := NewPool(PoolOptions{
+		Options:         Options{Address: server.Addr()},
+		MaxConnLifetime: time.Millisecond * 100,
+	})
+	defer pool.Close()
+
+	old, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+
+	time.Sleep(time.Millisecond * 60)
+
+	young, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+
+	// the old connection is the most recently used one, so it is the first to be handed out
+	pool.Release(young)
+	pool.Release(old)
+
+	time.Sleep(time.Millisecond * 50)
+
+	client, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+	assert.Same(t, young, client)
+	pool.Release(client)
+
+	assert.Equal(t, uint64(1), pool.Stats().StaleConns)
+}
+
+func TestPool_HealthCheck(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	pool := NewPool(PoolOptions{
+		Options:             Options{Address: server.Addr()},
+		HealthCheckInterval: time.Nanosecond,
+	})
+	defer pool.Close()
+
+	client, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+	pool.Release(client)
+
+	// the idle connection dies while in the pool
+	client.conn.Close()
+
+	result, err := pool.Do(context.Background(), "PING")
+	require.NoError(t, err)
+	assert.Equal(t, "PONG", result.Content())
+
+	assert.Equal(t, PoolStats{
+		Misses:     2,
+		StaleConns: 1,
+		TotalConns: 1,
+		IdleConns:  1,
+	}, pool.Stats())
+}
+
+func TestPool_HealthCheckWithDoneContext(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	pool := NewPool(PoolOptions{
+		Options:             Options{Address: server.Addr()},
+		HealthCheckInterval: time.Nanosecond,
+	})
+	defer pool.Close()
+
+	var clients []*Client
+	for x := 0; x < 5; x++ {
+		client, err := pool.Acquire(context.Background())
+		require.NoError(t, err)
+		clients = append(clients, client)
+	}
+
+	for _, client := range clients {
+		pool.Release(client)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = pool.Acquire(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.Equal(t, PoolStats{
+		Misses:     5,
+		TotalConns: 5,
+		IdleConns:  5,
+	}, pool.Stats())
+}
+
+func TestPool_HealthCheckInterruptedByContext(t *testing.T) {
+	// the server never answers, so the context ends while the PING waits for its reply
+	server := newFakeServer(t, func(w io.Writer, args []string) {})
+
+	pool := NewPool(PoolOptions{
+		Options:             Options{Address: server.Addr()},
+		HealthCheckInterval: time.Nanosecond,
+	})
+	defer pool.Close()
+
+	client, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+	pool.Release(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+
+	_, err = pool.Acquire(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the connection has a PING without a reply so it can't be reused, but it didn't fail the check
+	assert.Equal(t, PoolStats{
+		Misses: 1,
+	}, pool.Stats())
+}
+
+func TestPool_BrokenConnectionsAreClosed(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	pool := NewPool(PoolOptions{Options: Options{Address: server.Addr()}})
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+
+	_, err = pool.Do(ctx, "BLPOP", "some-list", 0)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.Equal(t, PoolStats{
+		Misses: 1,
+	}, pool.Stats())
+}
+
+func TestPool_Close(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	pool := NewPool(PoolOptions{Options: Options{Address: server.Addr()}})
+	defer pool.Close()
+
+	client, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, pool.Close())
+
+	_, err = pool.Acquire(context.Background())
+	assert.Equal(t, ErrPoolClosed, err)
+
+	pool.Release(client)
+	assert.Equal(t, 0, pool.Stats().TotalConns)
+}
@@ -0,0 +1,616 @@
+package redis_client
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPingInterval       = time.Second * 30
+	defaultMessageChannelSize = 100
+	minReconnectDelay         = time.Millisecond * 100
+	maxReconnectDelay         = time.Second * 5
+)
+
+var (
+	_ io.Closer = &PubSub{}
+
+	// ErrPubSubClosed is returned when using a closed PubSub.
+	ErrPubSubClosed = errors.New("redis: pubsub is closed")
+
+	// subscribeCommands are sent in this order to subscribe again after a reconnect
+	subscribeCommands = []string{"SUBSCRIBE", "PSUBSCRIBE", "SSUBSCRIBE"}
+)
+
+// Message is a message published on a channel the PubSub is subscribed to. Pattern is only set for messages
+// received because of a PSubscribe pattern.
+type Message struct {
+	Channel string
+	Pattern string
+	Payload string
+}
+
+// PubSubOptions configures a PubSub.
+type PubSubOptions struct {
+	// Options is how connections are created.
+	Options Options
+	// PingInterval is how often a PING is sent to check the connection is still alive, defaults to 30 seconds.
+	// if nothing arrives for PingInterval plus the read timeout the connection is replaced by a new one.
+	PingInterval time.Duration
+	// ChannelSize is how many messages wait on the Messages channel before the receive loop stops reading
+	// from the connection, defaults to 100.
+	ChannelSize int
+}
+
+func (o PubSubOptions) pingInterval() time.Duration {
+	if o.PingInterval == 0 {
+		return defaultPingInterval
+	}
+
+	return o.PingInterval
+}
+
+// PubSub receives messages from channels, patterns and sharded channels on a connection of its own, a
+// connection that has subscribed to something can't be used for other commands.
+//
+// a receive loop reads everything the server sends and delivers messages on the Messages channel. if the
+// connection fails or stops answering PINGs it is replaced by a new one and all subscriptions are made
+// again, messages published while there was no connection are lost.
+type PubSub struct {
+	options  PubSubOptions
+	messages chan Message
+	ctx      context.Context
+	cancel   context.CancelFunc
+	loops    sync.WaitGroup
+
+	mutex sync.Mutex
+	// client is nil while reconnecting, subscriptions made then are sent once the new connection is up
+	client        *Client
+	subscriptions map[string]map[string]bool
+	waiters       []*subscribeWaiter
+	closed        bool
+
+	// dropped is called with the sharded channels the server unsubscribed by itself, it must not block
+	dropped func(channels []string)
+}
+
+// subscribeWaiter is a subscribe call waiting for the server to confirm all its channels.
+type subscribeWaiter struct {
+	kind    string
+	pending map[string]bool
+	done    chan struct{}
+	err     error
+}
+
+func (w *subscribeWaiter) finish(err error) {
+	w.err = err
+	close(w.done)
+}
+
+// NewPubSub connects to the server and starts the receive loop, there are no subscriptions until
+// Subscribe or PSubscribe are called.
+func NewPubSub(ctx context.Context, options PubSubOptions) (*PubSub, error) {
+	return newPubSub(ctx, options, nil)
+}
+
+func newPubSub(ctx context.Context, options PubSubOptions, dropped func(channels []string)) (*PubSub, error) {
+	if options.ChannelSize == 0 {
+		options.ChannelSize = defaultMessageChannelSize
+	}
+
+	client, err := ConnectWithOptions(ctx, options.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &PubSub{
+		options:       options,
+		messages:      make(chan Message, options.ChannelSize),
+		client:        client,
+		subscriptions: map[string]map[string]bool{},
+		dropped:       dropped,
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	for _, command := range subscribeCommands {
+		p.subscriptions[command] = map[string]bool{}
+	}
+
+	p.loops.Add(2)
+	go p.receiveLoop(client)
+	go p.pingLoop()
+
+	return p, nil
+}
+
+// Messages returns the channel messages are delivered on, it is closed once the PubSub is closed.
+func (p *PubSub) Messages() <-chan Message {
+	return p.messages
+}
+
+// Subscribe subscribes to the channels and waits for the server to confirm them. if the context is done
+// before that the subscriptions are kept and still made once the server answers, use Unsubscribe to drop them.
+func (p *PubSub) Subscribe(ctx context.Context, channels ...string) error {
+	return p.subscribe(ctx, "SUBSCRIBE", channels)
+}
+
+// PSubscribe subscribes to the glob-style patterns and waits for the server to confirm them, like Subscribe.
+func (p *PubSub) PSubscribe(ctx context.Context, patterns ...string) error {
+	return p.subscribe(ctx, "PSUBSCRIBE", patterns)
+}
+
+// SSubscribe subscribes to sharded channels and waits for the server to confirm them, like Subscribe. sharded
+// channels are only sent to the nodes in the shard that owns their slot, on a cluster all channels on a
+// single call must map to the same slot.
+func (p *PubSub) SSubscribe(ctx context.Context, channels ...string) error {
+	return p.subscribe(ctx, "SSUBSCRIBE", channels)
+}
+
+// Unsubscribe unsubscribes from the channels, or from all channels if none are given. messages that were
+// already on the way can still be delivered after it returns.
+func (p *PubSub) Unsubscribe(channels ...string) error {
+	return p.unsubscribe("SUBSCRIBE", "UNSUBSCRIBE", channels)
+}
+
+// PUnsubscribe unsubscribes from the patterns, or from all patterns if none are given, like Unsubscribe.
+func (p *PubSub) PUnsubscribe(patterns ...string) error {
+	return p.unsubscribe("PSUBSCRIBE", "PUNSUBSCRIBE", patterns)
+}
+
+// SUnsubscribe unsubscribes from the sharded channels, or from all sharded channels if none are given,
+// like Unsubscribe.
+func (p *PubSub) SUnsubscribe(channels ...string) error {
+	return p.unsubscribe("SSUBSCRIBE", "SUNSUBSCRIBE", channels)
+}
+
+// Publish posts the message on the channel and returns how many clients received it.
+func (c *Commands) Publish(ctx context.Context, channel string, message interface{}) (int64, error) {
+	return c.int64(ctx, "PUBLISH", channel, message)
+}
+
+// SPublish posts the message on the sharded channel and returns how many clients received it.
+func (c *Commands) SPublish(ctx context.Context, channel string, message interface{}) (int64, error) {
+	return c.int64(ctx, "SPUBLISH", channel, message)
+}
+
+// Close stops the receive loop, closes the connection and closes the Messages channel.
+func (p *PubSub) Close() error {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return nil
+	}
+
+	p.closed = true
+	client := p.client
+	waiters := p.waiters
+	p.waiters = nil
+	p.mutex.Unlock()
+
+	p.cancel()
+
+	// the receive loop closes the connection once the blocked read is interrupted
+	if client != nil {
+		client.conn.SetDeadline(aLongTimeAgo)
+	}
+
+	for _, waiter := range waiters {
+		waiter.finish(ErrPubSubClosed)
+	}
+
+	p.loops.Wait()
+
+	return nil
+}
+
+func (p *PubSub) subscribe(ctx context.Context, command string, names []string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("%v needs at least one channel", command)
+	}
+
+	waiter := &subscribeWaiter{
+		kind:    strings.ToLower(command),
+		pending: make(map[string]bool, len(names)),
+		done:    make(chan struct{}),
+	}
+
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return ErrPubSubClosed
+	}
+
+	for _, name := range names {
+		p.subscriptions[command][name] = true
+		waiter.pending[name] = true
+	}
+
+	p.waiters = append(p.waiters, waiter)
+
+	if err := p.write(command, names); err != nil {
+		p.removeWaiter(waiter)
+		p.mutex.Unlock()
+		return err
+	}
+	p.mutex.Unlock()
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.options.Options.writeTimeout()+p.options.Options.readTimeout())
+		defer cancel()
+	}
+
+	select {
+	case <-waiter.done:
+		return waiter.err
+	case <-ctx.Done():
+		p.mutex.Lock()
+		p.removeWaiter(waiter)
+		p.mutex.Unlock()
+
+		return errors.Wrapf(ctx.Err(), "operation %v interrupted", command)
+	}
+}
+
+func (p *PubSub) unsubscribe(subscribeCommand string, command string, names []string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return ErrPubSubClosed
+	}
+
+	if len(names) == 0 {
+		p.subscriptions[subscribeCommand] = map[string]bool{}
+	}
+
+	for _, name := range names {
+		delete(p.subscriptions[subscribeCommand], name)
+	}
+
+	return p.write(command, names)
+}
+
+// write sends a command on the current connection, the mutex must be held. without a connection there is
+// nothing to do as reconnecting subscribes to everything again. if the write fails the connection is closed
+// so the receive loop replaces it.
+func (p *PubSub) write(args ...interface{}) error {
+	if p.client == nil || p.closed {
+		return nil
+	}
+
+	p.client.conn.SetWriteDeadline(time.Now().Add(p.options.Options.writeTimeout()))
+
+	if err := p.client.writer.WriteCommand(args...); err != nil {
+		return errors.Wrapf(err, "failed to execute operation: %v", args[0])
+	}
+
+	if err := p.client.writer.Flush(); err != nil {
+		p.client.Close()
+	}
+
+	return nil
+}
+
+// removeWaiter removes a waiter that gave up, the mutex must be held.
+func (p *PubSub) removeWaiter(waiter *subscribeWaiter) {
+	for x, w := range p.waiters {
+		if w == waiter {
+			p.waiters = append(p.waiters[:x], p.waiters[x+1:]...)
+			return
+		}
+	}
+}
+
+// confirm marks the subscription as confirmed by the server on the first waiter expecting it.
+func (p *PubSub) confirm(kind string, name string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for x, waiter := range p.waiters {
+		if waiter.kind != kind || !waiter.pending[name] {
+			continue
+		}
+
+		delete(waiter.pending, name)
+		if len(waiter.pending) == 0 {
+			p.waiters = append(p.waiters[:x], p.waiters[x+1:]...)
+			waiter.finish(nil)
+		}
+
+		return
+	}
+}
+
+// reject fails the oldest waiter, errors are the only replies that don't say which subscription they are for.
+// the channels the server didn't confirm are dropped so they aren't tried again after a reconnect. a MOVED
+// error nobody is waiting for comes from subscribing again after a reconnect to a node that doesn't own
+// the slot anymore, the sharded channels on that slot are dropped.
+func (p *PubSub) reject(err error) {
+	p.mutex.Lock()
+
+	if len(p.waiters) == 0 {
+		var channels []string
+		if slot, ok := movedSlot(err); ok {
+			for channel := range p.subscriptions["SSUBSCRIBE"] {
+				if KeySlot(channel) == slot {
+					channels = append(channels, channel)
+				}
+			}
+		}
+		p.mutex.Unlock()
+
+		p.drop(channels)
+		return
+	}
+	defer p.mutex.Unlock()
+
+	waiter := p.waiters[0]
+	p.waiters = p.waiters[1:]
+
+	for name := range waiter.pending {
+		delete(p.subscriptions[strings.ToUpper(waiter.kind)], name)
+	}
+
+	waiter.finish(err)
+}
+
+func (p *PubSub) receiveLoop(client *Client) {
+	defer p.loops.Done()
+	defer close(p.messages)
+
+	for client != nil {
+		p.receive(client)
+		client.Close()
+
+		p.mutex.Lock()
+		p.client = nil
+		p.mutex.Unlock()
+
+		client = p.reconnect()
+	}
+}
+
+// receive reads from the connection until it fails.
+func (p *PubSub) receive(client *Client) error {
+	timeout := p.options.pingInterval() + p.options.Options.readTimeout()
+
+	for {
+		client.conn.SetReadDeadline(time.Now().Add(timeout))
+
+		result, err := client.reader.Read()
+		if err != nil {
+			return err
+		}
+
+		if err := result.Err(); err != nil {
+			p.reject(err)
+			continue
+		}
+
+		kind, values, ok := pubSubFrame(result.content)
+		if !ok {
+			// RESP3 replies to PING are a plain PONG
+			continue
+		}
+
+		switch kind {
+		case "message", "smessage":
+			if len(values) != 2 {
+				return fmt.Errorf("invalid %v frame: %#v", kind, result.content)
+			}
+
+			message := Message{}
+			message.Channel, _ = stringValue(values[0])
+			message.Payload, _ = stringValue(values[1])
+
+			if err := p.deliver(message); err != nil {
+				return err
+			}
+		case "pmessage":
+			if len(values) != 3 {
+				return fmt.Errorf("invalid pmessage frame: %#v", result.content)
+			}
+
+			message := Message{}
+			message.Pattern, _ = stringValue(values[0])
+			message.Channel, _ = stringValue(values[1])
+			message.Payload, _ = stringValue(values[2])
+
+			if err := p.deliver(message); err != nil {
+				return err
+			}
+		case "subscribe", "psubscribe", "ssubscribe":
+			if len(values) > 0 {
+				name, _ := stringValue(values[0])
+				p.confirm(kind, name)
+			}
+		case "sunsubscribe":
+			// these confirm SUnsubscribe calls or say the slot of the channel moved to another node
+			if len(values) > 0 {
+				name, _ := stringValue(values[0])
+
+				p.mutex.Lock()
+				subscribed := p.subscriptions["SSUBSCRIBE"][name]
+				p.mutex.Unlock()
+
+				if subscribed {
+					p.drop([]string{name})
+				}
+			}
+		}
+	}
+}
+
+// drop removes sharded channels the server unsubscribed by itself.
+func (p *PubSub) drop(channels []string) {
+	if len(channels) == 0 {
+		return
+	}
+
+	p.mutex.Lock()
+	for _, channel := range channels {
+		delete(p.subscriptions["SSUBSCRIBE"], channel)
+	}
+	p.mutex.Unlock()
+
+	if p.dropped != nil {
+		p.dropped(channels)
+	}
+}
+
+// movedSlot returns the slot on a MOVED error.
+func movedSlot(err error) (int, bool) {
+	var redisErr *RedisError
+	if !errors.As(err, &redisErr) || redisErr.Prefix != PrefixMoved {
+		return 0, false
+	}
+
+	fields := strings.Fields(redisErr.Message)
+	if len(fields) == 0 {
+		return 0, false
+	}
+
+	slot, err := strconv.Atoi(fields[0])
+	return slot, err == nil
+}
+
+func (p *PubSub) deliver(message Message) error {
+	select {
+	case p.messages <- message:
+		return nil
+	case <-p.ctx.Done():
+		return ErrPubSubClosed
+	}
+}
+
+// reconnect opens a new connection and subscribes to everything again, waiting longer after each failure.
+// it returns nil once the PubSub is closed.
+func (p *PubSub) reconnect() *Client {
+	var client *Client
+
+	retryWithBackoff(p.ctx, func() bool {
+		connected, err := ConnectWithOptions(p.ctx, p.options.Options)
+		if err != nil {
+			return false
+		}
+
+		if err := p.resubscribe(connected); err != nil {
+			connected.Close()
+			return false
+		}
+
+		client = connected
+		return true
+	})
+
+	return client
+}
+
+// retryWithBackoff calls fn until it returns true, waiting minReconnectDelay after the first failure and twice
+// as long after every other one, up to maxReconnectDelay. it returns false if ctx is done before that.
+func retryWithBackoff(ctx context.Context, fn func() bool) bool {
+	delay := minReconnectDelay
+
+	for {
+		if fn() {
+			return true
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		}
+
+		if delay *= 2; delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// resubscribe sends all subscriptions on the new connection and makes it the current one.
+func (p *PubSub) resubscribe(client *Client) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return ErrPubSubClosed
+	}
+
+	client.conn.SetWriteDeadline(time.Now().Add(p.options.Options.writeTimeout()))
+
+	for _, command := range subscribeCommands {
+		if len(p.subscriptions[command]) == 0 {
+			continue
+		}
+
+		names := make([]string, 0, len(p.subscriptions[command]))
+		for name := range p.subscriptions[command] {
+			names = append(names, name)
+		}
+
+		if err := client.writer.WriteCommand(command, names); err != nil {
+			return err
+		}
+	}
+
+	if err := client.writer.Flush(); err != nil {
+		return err
+	}
+
+	p.client = client
+
+	return nil
+}
+
+// pingLoop sends a PING every interval so a connection that stopped answering hits the read deadline.
+func (p *PubSub) pingLoop() {
+	defer p.loops.Done()
+
+	ticker := time.NewTicker(p.options.pingInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.mutex.Lock()
+			p.write("PING")
+			p.mutex.Unlock()
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// pubSubFrame returns the kind of a pub/sub frame, like message or subscribe, and the values after it.
+// frames are RESP3 pushes or RESP2 arrays.
+func pubSubFrame(content interface{}) (string, []interface{}, bool) {
+	var values []interface{}
+
+	switch t := content.(type) {
+	case Push:
+		values = t
+	case []interface{}:
+		values = t
+	}
+
+	if len(values) == 0 {
+		return "", nil, false
+	}
+
+	kind, err := stringValue(values[0])
+	if err != nil {
+		return "", nil, false
+	}
+
+	return strings.ToLower(kind), values[1:], true
+}
@@ -0,0 +1,255 @@
+package redis_client
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func receiveMessage(t *testing.T, p *PubSub) Message {
+	t.Helper()
+
+	select {
+	case message := <-p.Messages():
+		return message
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+	}
+
+	return Message{}
+}
+
+func TestPubSub_Subscribe(t *testing.T) {
+	for _, protocol := range []int{2, 3} {
+		t.Run("RESP"+strconv.Itoa(protocol), func(t *testing.T) {
+			server, err := miniredis.Run()
+			require.NoError(t, err)
+			defer server.Close()
+
+			p, err := NewPubSub(context.Background(), PubSubOptions{Options: Options{Address: server.Addr(), Protocol: protocol}})
+			require.NoError(t, err)
+			defer p.Close()
+
+			ctx := context.Background()
+
+			require.NoError(t, p.Subscribe(ctx, "news", "sports"))
+			require.NoError(t, p.PSubscribe(ctx, "weather.*"))
+
+			assert.Equal(t, map[string]int{"news": 1, "sports": 1}, server.PubSubNumSub("news", "sports"))
+			assert.Equal(t, 1, server.PubSubNumPat())
+
+			server.Publish("news", "hello")
+			assert.Equal(t, Message{Channel: "news", Payload: "hello"}, receiveMessage(t, p))
+
+			server.Publish("weather.today", "sunny")
+			assert.Equal(t, Message{Channel: "weather.today", Pattern: "weather.*", Payload: "sunny"}, receiveMessage(t, p))
+
+			require.NoError(t, p.Unsubscribe("news"))
+			require.NoError(t, p.PUnsubscribe())
+
+			require.Eventually(t, func() bool {
+				return server.PubSubNumSub("news")["news"] == 0 && server.PubSubNumPat() == 0
+			}, time.Second, time.Millisecond*10)
+
+			server.Publish("news", "ignored")
+			server.Publish("weather.today", "ignored")
+			server.Publish("sports", "goal")
+			assert.Equal(t, Message{Channel: "sports", Payload: "goal"}, receiveMessage(t, p))
+		})
+	}
+}
+
+func TestPubSub_Resubscribes(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	p, err := NewPubSub(context.Background(), PubSubOptions{Options: Options{Address: server.Addr()}})
+	require.NoError(t, err)
+	defer p.Close()
+
+	ctx := context.Background()
+
+	require.NoError(t, p.Subscribe(ctx, "news"))
+	require.NoError(t, p.PSubscribe(ctx, "weather.*"))
+
+	server.Close()
+	require.NoError(t, server.Restart())
+
+	require.Eventually(t, func() bool {
+		return server.PubSubNumSub("news")["news"] == 1 && server.PubSubNumPat() == 1
+	}, time.Second*2, time.Millisecond*10)
+
+	server.Publish("news", "back")
+	assert.Equal(t, Message{Channel: "news", Payload: "back"}, receiveMessage(t, p))
+
+	server.Publish("weather.today", "rain")
+	assert.Equal(t, Message{Channel: "weather.today", Pattern: "weather.*", Payload: "rain"}, receiveMessage(t, p))
+}
+
+func TestPubSub_ReplacesDeadConnections(t *testing.T) {
+	server := newFakeServer(t, func(w io.Writer, args []string) {
+		// PINGs are never answered
+		if args[0] == "SUBSCRIBE" {
+			io.WriteString(w, ">3\r\n$9\r\nsubscribe\r\n$"+strconv.Itoa(len(args[1]))+"\r\n"+args[1]+"\r\n:1\r\n")
+		}
+	})
+
+	p, err := NewPubSub(context.Background(), PubSubOptions{
+		Options:      Options{Address: server.Addr(), ReadTimeout: time.Millisecond * 50},
+		PingInterval: time.Millisecond * 20,
+	})
+	require.NoError(t, err)
+	defer p.Close()
+
+	require.NoError(t, p.Subscribe(context.Background(), "news"))
+
+	require.Eventually(t, func() bool {
+		subscribes := 0
+		for _, command := range server.Commands() {
+			if command[0] == "SUBSCRIBE" {
+				subscribes++
+			}
+		}
+
+		return subscribes >= 2
+	}, time.Second*2, time.Millisecond*10)
+
+	assert.Contains(t, server.Commands(), []string{"PING"})
+}
+
+func TestPubSub_SubscribeErrors(t *testing.T) {
+	server := newFakeServer(t, func(w io.Writer, args []string) {
+		if args[0] == "SUBSCRIBE" {
+			io.WriteString(w, "-NOPERM this user has no permissions to access the channel\r\n")
+		}
+	})
+
+	p, err := NewPubSub(context.Background(), PubSubOptions{Options: Options{Address: server.Addr()}})
+	require.NoError(t, err)
+	defer p.Close()
+
+	err = p.Subscribe(context.Background(), "secret")
+	assert.True(t, HasPrefix(err, PrefixNoPerm), "expected a NOPERM error but got %v", err)
+
+	err = p.Subscribe(context.Background())
+	assert.EqualError(t, err, "SUBSCRIBE needs at least one channel")
+}
+
+func TestPubSub_Close(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	p, err := NewPubSub(context.Background(), PubSubOptions{Options: Options{Address: server.Addr()}})
+	require.NoError(t, err)
+	defer p.Close()
+
+	require.NoError(t, p.Subscribe(context.Background(), "news"))
+	require.NoError(t, p.Close())
+
+	_, open := <-p.Messages()
+	assert.False(t, open)
+
+	assert.Equal(t, ErrPubSubClosed, p.Subscribe(context.Background(), "news"))
+	assert.Equal(t, ErrPubSubClosed, p.Unsubscribe())
+	assert.NoError(t, p.Close())
+}
+
+func TestPubSub_SSubscribe(t *testing.T) {
+	server := newFakeServer(t, func(w io.Writer, args []string) {
+		switch args[0] {
+		case "SSUBSCRIBE":
+			for _, channel := range args[1:] {
+				io.WriteString(w, "*3\r\n$10\r\nssubscribe\r\n$"+strconv.Itoa(len(channel))+"\r\n"+channel+"\r\n:1\r\n")
+			}
+
+			io.WriteString(w, "*3\r\n$8\r\nsmessage\r\n$6\r\norders\r\n$7\r\ncreated\r\n")
+		case "SUNSUBSCRIBE":
+			io.WriteString(w, "*3\r\n$12\r\nsunsubscribe\r\n$6\r\norders\r\n:0\r\n")
+		case "SPUBLISH":
+			io.WriteString(w, ":1\r\n")
+		}
+	})
+
+	p, err := NewPubSub(context.Background(), PubSubOptions{Options: Options{Address: server.Addr()}})
+	require.NoError(t, err)
+	defer p.Close()
+
+	require.NoError(t, p.SSubscribe(context.Background(), "orders", "{orders}.paid"))
+	assert.Equal(t, Message{Channel: "orders", Payload: "created"}, receiveMessage(t, p))
+	require.NoError(t, p.SUnsubscribe("orders"))
+
+	client, err := Connect(context.Background(), server.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+
+	received, err := client.SPublish(context.Background(), "orders", "paid")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), received)
+
+	require.Eventually(t, func() bool {
+		return len(server.Commands()) == 3
+	}, time.Second, time.Millisecond*10)
+
+	// the subscriber and the publisher are on different connections, so they can arrive in any order
+	assert.ElementsMatch(t, [][]string{
+		{"SSUBSCRIBE", "orders", "{orders}.paid"},
+		{"SUNSUBSCRIBE", "orders"},
+		{"SPUBLISH", "orders", "paid"},
+	}, server.Commands())
+}
+
+func TestCommands_Publish(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	p, err := NewPubSub(context.Background(), PubSubOptions{Options: Options{Address: server.Addr()}})
+	require.NoError(t, err)
+	defer p.Close()
+
+	require.NoError(t, p.Subscribe(context.Background(), "news"))
+
+	client, err := Connect(context.Background(), server.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+
+	received, err := client.Publish(context.Background(), "news", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), received)
+
+	received, err = client.Publish(context.Background(), "nobody", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), received)
+
+	assert.Equal(t, Message{Channel: "news", Payload: "hello"}, receiveMessage(t, p))
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	calls := 0
+	started := time.Now()
+
+	assert.True(t, retryWithBackoff(context.Background(), func() bool {
+		calls++
+		return calls == 3
+	}))
+	assert.Equal(t, 3, calls)
+	// waits 100ms and then 200ms
+	assert.GreaterOrEqual(t, time.Since(started), minReconnectDelay*3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+
+	calls = 0
+	assert.False(t, retryWithBackoff(ctx, func() bool {
+		calls++
+		return false
+	}))
+	assert.Equal(t, 1, calls)
+}
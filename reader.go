@@ -2,22 +2,47 @@ package redis_client
 
 import (
 	"bufio"
-	"bytes"
 	"encoding/base64"
-	"errors"
 	"fmt"
 	pkgerrors "github.com/pkg/errors"
 	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
 const (
 	defaultBufferLength = 10140
-	typeSimpleString    = '+'
-	typeErorr           = '-'
-	typeInteger         = ':'
-	typeBulkString      = '$'
-	typeArray           = '*'
+	// defaultMaxBulkLength is the same as the default `proto-max-bulk-len` on the server, 512MB.
+	defaultMaxBulkLength = 512 * 1024 * 1024
+	// maxAggregateLength is the max number of items in an array, set, push or map. lengths over it can only
+	// come from a broken stream and would not fit the slices the items are read into.
+	maxAggregateLength = math.MaxInt32
+	// maxAggregateCapacity is how many items are allocated before reading an aggregate, bigger aggregates grow
+	// as the items arrive so a length header alone can't allocate memory for items that were never sent.
+	maxAggregateCapacity = 1024
+	// maxBulkCapacity is how many bytes are allocated before reading a bulk string, bigger values grow as the
+	// data arrives, for the same reason as maxAggregateCapacity.
+	maxBulkCapacity  = 64 * 1024
+	typeSimpleString = '+'
+	typeErorr        = '-'
+	typeInteger      = ':'
+	typeBulkString   = '$'
+	typeArray        = '*'
+
+	// RESP3 types, servers only send these after a connection has switched to protocol 3 with `HELLO 3`
+	typeNull           = '_'
+	typeDouble         = ','
+	typeBoolean        = '#'
+	typeBigNumber      = '('
+	typeBlobError      = '!'
+	typeVerbatimString = '='
+	typeMap            = '%'
+	typeSet            = '~'
+	typeAttribute      = '|'
+	typePush           = '>'
 )
 
 var (
@@ -25,229 +50,467 @@ var (
 )
 
 type Reader struct {
-	scanner *bufio.Scanner
+	reader        *bufio.Reader
+	maxBulkLength int64
+	// line holds lines that didn't fit the bufio.Reader buffer, it is reused across reads
+	line []byte
 }
 
 func NewReader(r io.Reader) *Reader {
-	scanner := bufio.NewScanner(bufio.NewReaderSize(r, defaultBufferLength))
-	scanner.Split(redisSplitter)
+	return NewReaderLimit(r, defaultMaxBulkLength)
+}
 
+// NewReaderLimit creates a reader that fails to read any bulk string (or simple string, error and
+// verbatim string) larger than maxBulkLength bytes. the limit does not apply to ReadBulkTo as it
+// never holds the whole value in memory.
+func NewReaderLimit(r io.Reader, maxBulkLength int64) *Reader {
 	return &Reader{
-		scanner: scanner,
+		reader:        bufio.NewReaderSize(r, defaultBufferLength),
+		maxBulkLength: maxBulkLength,
 	}
 }
 
 func (r *Reader) Read() (*Result, error) {
-	return readRESP(r.scanner)
+	return readRESP(r)
+}
+
+// ReadBulkTo reads the next reply and copies it to w as it comes from the stream, without buffering
+// the whole value in memory, so values of any size can be sent to files or HTTP responses. the reply must
+// be a bulk string, a null reply returns ErrNil and an error reply returns the error itself. if w fails
+// the rest of the value is discarded so the reader can still be used.
+func (r *Reader) ReadBulkTo(w io.Writer) (int64, error) {
+	written, replyErr, err := r.readBulkTo(w)
+	if err != nil {
+		return written, err
+	}
+
+	return written, replyErr
+}
+
+// readBulkTo separates errors that leave the stream in a good state (like error replies or a failing
+// writer) from errors reading the stream itself, after these the reader can't be used anymore.
+func (r *Reader) readBulkTo(w io.Writer) (int64, error, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	switch line[0] {
+	case typeBulkString:
+		length, err := parseLength(line)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if length == -1 {
+			return 0, ErrNil, nil
+		}
+
+		written, writeErr := io.CopyN(w, r.reader, length)
+		if writeErr != nil {
+			if _, err := io.CopyN(ioutil.Discard, r.reader, length-written); err != nil {
+				return written, nil, pkgerrors.Wrap(unexpectedEOF(err), "failed to read bulk string")
+			}
+		}
+
+		if err := r.readSeparator(); err != nil {
+			return written, nil, err
+		}
+
+		return written, writeErr, nil
+	case typeNull:
+		return 0, ErrNil, nil
+	}
+
+	result, err := r.readValue(line)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := result.Err(); err != nil {
+		return 0, err, nil
+	}
+
+	return 0, fmt.Errorf("reply is not a bulk string: %#v", result.Content()), nil
 }
 
-// redisSplitter splits a byte stream into full lines in the redis protocol format. it reads a whole item
-// (string, bulk string, error, integer or array) and then returns it as a line. code reading from scanners
-// created with this function can safely assume that if they got a line it's a full line that does not need
-// any extra checking.
-func redisSplitter(data []byte, atEOF bool) (advance int, token []byte, err error) {
-	// a valid redis message has at least 3 characters, if less than that ask for more stuff
-	if len(data) < 3 {
+// readLine reads a full line from the stream and returns it without the \r\n at the end. the returned
+// slice is only valid until the next read.
+func (r *Reader) readLine() ([]byte, error) {
+	line, err := r.reader.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// the line is bigger than our buffer, keep on reading it into our own buffer
+		r.line = append(r.line[:0], line...)
+		for err == bufio.ErrBufferFull {
+			if int64(len(r.line)) > r.maxBulkLength {
+				return nil, fmt.Errorf("line is bigger than the max length of %v bytes", r.maxBulkLength)
+			}
+
+			line, err = r.reader.ReadSlice('\n')
+			r.line = append(r.line, line...)
+		}
+		line = r.line
+	}
 
-		if atEOF {
-			return 0, nil, fmt.Errorf("unexpected end of stream, a redis message needs at least 3 characters to be valid, actual content in base64: [%v]", base64.RawStdEncoding.EncodeToString(data))
+	if err != nil {
+		if err == io.EOF && len(line) > 0 {
+			return nil, fmt.Errorf("unexpected end of stream, there should have been a \\r\\n before the end, actual content in base64: [%v]", base64.RawStdEncoding.EncodeToString(line))
 		}
 
-		return 0, nil, nil
+		return nil, err
+	}
+
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("invalid line, a redis message needs a type and to end with \\r\\n, actual content in base64: [%v]", base64.RawStdEncoding.EncodeToString(line))
+	}
+
+	return line[:len(line)-2], nil
+}
+
+// readSeparator reads the \r\n that closes bulk strings.
+func (r *Reader) readSeparator() error {
+	var end [2]byte
+	if _, err := io.ReadFull(r.reader, end[:]); err != nil {
+		return unexpectedEOF(err)
+	}
+
+	if end[0] != '\r' || end[1] != '\n' {
+		return fmt.Errorf("bulk string does not end with \\r\\n, actual content in base64: [%v]", base64.RawStdEncoding.EncodeToString(end[:]))
 	}
 
-	found := bytes.Index(data, separator)
-	// if we could not find a \r\n and the stream is at its end, this stream is broken and can't be recovered
-	if found == -1 && atEOF {
-		return 0, nil, fmt.Errorf("unexpected end of stream, there should have been a \\r\\n before the end, actual content in base64: [%v]", base64.RawStdEncoding.EncodeToString(data))
+	return nil
+}
+
+// readBulk reads length bytes from the stream. bulk strings, verbatim strings and blob errors change the
+// binary format from a terminator based one (\r\n to close messages) to a length based one, it is not safe
+// to just find the next \r\n as it could be part of the value itself, we always have to read the length.
+func (r *Reader) readBulk(length int64) (string, error) {
+	if length > r.maxBulkLength {
+		return "", fmt.Errorf("bulk string of %v bytes is bigger than the max length of %v bytes", length, r.maxBulkLength)
+	}
+
+	capacity := length
+	if capacity > maxBulkCapacity {
+		capacity = maxBulkCapacity
+	}
+
+	builder := strings.Builder{}
+	builder.Grow(int(capacity))
+
+	if _, err := io.CopyN(&builder, r.reader, length); err != nil {
+		return "", pkgerrors.Wrapf(unexpectedEOF(err), "failed to read bulk string with %v bytes", length)
+	}
+
+	if err := r.readSeparator(); err != nil {
+		return "", err
+	}
+
+	return builder.String(), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+func parseLength(line []byte) (int64, error) {
+	length, err := strconv.ParseInt(string(line[1:]), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse length: %v (value: %v)", err, string(line))
 	}
 
-	// if there is no \r\n we need to read more data, this means this result isn't finished yet
-	if found == -1 {
-		return 0, nil, nil
+	if length < -1 {
+		return 0, fmt.Errorf("invalid length: %v", string(line))
+	}
+
+	return length, nil
+}
+
+// readRESP reads a full reply from the reader, aggregates like arrays and maps are read recursively
+// until all their items have been read.
+func readRESP(r *Reader) (*Result, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
 	}
 
-	// bulk strings are special, they change the binary format from a terminator based one (\r\n to close messages)
-	// to a length based one. so once we figure out this message is a bulk string (like `$6\r\nfoobar\r\n`),
-	// we have to read the length and make sure there are at least length + 2 bytes after the first \r\n to
-	// show that we do have the whole bulk string here. it is not safe to just find all \r\n in a bulk string
-	// because there could be \r\n tokens as part of the string itself, so we always have to make sure we consume
-	// the length and use it to read the whole value.
-	if data[0] == typeBulkString {
-		length, err := strconv.ParseInt(string(data[1:found]), 10, 64)
+	return r.readValue(line)
+}
+
+func (r *Reader) readValue(line []byte) (*Result, error) {
+	switch line[0] {
+	case typeSimpleString:
+		// if a string, just remove the marker and return it
+		return &Result{
+			content: string(line[1:]),
+		}, nil
+	case typeBulkString:
+		length, err := parseLength(line)
 		if err != nil {
-			return 0, nil, fmt.Errorf("message starts as bulk string but length is not a valid int, actual content in base64: [%v]", base64.RawStdEncoding.EncodeToString(data[0:found]))
+			return nil, err
 		}
 
 		// a -1 length means this is a null string and should be returned as such to clients, null and empty
-		// strings are different things in redis. this is the only time we return a bulk string sign ($) here
-		// as we'll use it as a marker for null strings. for someone reading from a scanner
-		// there should be no difference between a simple or a bulk string as we have already
-		// parsed the lengh and we'll return only the actual string contents.
+		// strings are different things in redis.
 		if length == -1 {
-			return 5, []byte("$"), nil
+			return &Result{
+				content: nil,
+			}, nil
+		}
+
+		content, err := r.readBulk(length)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Result{
+			content: content,
+		}, nil
+	case typeErorr:
+		// if an error just wrap the error and return it
+		return &Result{
+			content: parseRedisError(string(line[1:])),
+		}, nil
+	case typeBlobError:
+		content, err := r.readBlob(line)
+		if err != nil {
+			return nil, err
 		}
 
-		// a 0 length means an empty string, an empty string is not the same as a null string on redis
-		if length == 0 {
-			return 6, []byte("+"), nil
+		return &Result{
+			content: parseRedisError(content),
+		}, nil
+	case typeInteger:
+		content, err := strconv.ParseInt(string(line[1:]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse returned integer: %v (value: %v)", err, string(line))
+		}
+		return &Result{
+			content: content,
+		}, nil
+	case typeArray:
+		// the first thing to be done when we find an array is to find its length, if not `-1` we then
+		// read items from the stream until we've read all items on the array.
+		length, err := parseLength(line)
+		if err != nil {
+			return nil, err
 		}
 
-		// this is the position of the first \r\n + the expected length + 4 which is the \r\n twice we have on bulk strings
-		expectedEnding := found + int(length) + 4
-		if len(data) >= expectedEnding {
-			// given here we already have all the information we need to return this as a string,
-			// we don't return the length anymore, we return this as if it was a normal string.
-			// now we set the first `\n` we have to `+` so the code parses it as a simple string
-			// as we have already capped the returned slice do the length of the string.
+		if length == -1 {
+			return &Result{content: nil}, nil
+		}
 
-			start := found + 1
-			data[start] = '+'
-			return expectedEnding, data[start : expectedEnding-2], nil
+		contents, err := readItems(r, length)
+		if err != nil {
+			return nil, err
 		}
 
-		if atEOF {
-			return 0, nil, fmt.Errorf("unexpected end of stream, stream ends before bulk string has ended, expected there to be %v total bytes but there were only %v, actual content in base64: %v", expectedEnding, len(data), base64.RawStdEncoding.EncodeToString(data))
+		return &Result{
+			content: contents,
+		}, nil
+	case typeNull:
+		return &Result{content: nil}, nil
+	case typeDouble:
+		return readDouble(line)
+	case typeBoolean:
+		return readBoolean(line)
+	case typeBigNumber:
+		return readBigNumber(line)
+	case typeVerbatimString:
+		content, err := r.readBlob(line)
+		if err != nil {
+			return nil, err
 		}
 
-		return 0, nil, err
+		return readVerbatimString(content)
+	case typeSet:
+		return readSet(r, line)
+	case typePush:
+		return readPush(r, line)
+	case typeMap:
+		return readMap(r, line)
+	case typeAttribute:
+		return readAttribute(r, line)
 	}
 
-	return found + 2, data[:found], nil
+	return nil, fmt.Errorf("unknown RESP type %q, actual content in base64: [%v]", line[0], base64.RawStdEncoding.EncodeToString(line))
 }
 
-// readRESP reads from a scanner that was initiated with `redisSplitter`. it expects every scanned line to be
-// a full line of a data type redis supports (unless it's an array, arrays start with the length of the array only).
-func readRESP(r *bufio.Scanner) (*Result, error) {
-
-	for r.Scan() {
-		line := r.Text()
-		switch line[0] {
-		case typeSimpleString:
-			// if a string, just remove the marker and return it
-			return &Result{
-				content: line[1:],
-			}, nil
-		case typeBulkString:
-			// a bulk string is only returned if it is nil, otherwise it is turned as a simple string
-			return &Result{
-				content: nil,
-			}, nil
-		case typeErorr:
-			// if an error just wrap the error and return it
-			return &Result{
-				content: errors.New(line[1:]),
-			}, nil
-		case typeInteger:
-			content, err := strconv.ParseInt(line[1:], 10, 64)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse returned integer: %v (value: %v)", err, line)
-			}
-			return &Result{
-				content: content,
-			}, nil
-		case typeArray:
-			// the first thing to be done when we find an array is to find its length, if not `-1` we then
-			// read items from the scanner until we've read all items on the array.
-			length, err := strconv.ParseInt(line[1:], 10, 64)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse array length: %v (value: %v)", err, line)
-			}
+// readBlob reads the contents of length prefixed types that can't be null, like blob errors and verbatim strings.
+func (r *Reader) readBlob(line []byte) (string, error) {
+	length, err := readLength(line)
+	if err != nil {
+		return "", err
+	}
 
-			if length == -1 {
-				return &Result{content: nil}, nil
-			}
+	return r.readBulk(length)
+}
 
-			contents := make([]interface{}, 0, length)
+// readItems reads the next `length` items from the reader, this is what arrays, sets and pushes are made of.
+func readItems(r *Reader, length int64) ([]interface{}, error) {
+	if length > maxAggregateLength {
+		return nil, fmt.Errorf("aggregate of %v items is bigger than the max length of %v items", length, maxAggregateLength)
+	}
 
-			for x := int64(0); x < length; x++ {
-				result, err := readRESP(r)
-				if err != nil {
-					return nil, pkgerrors.Wrapf(err, "failed to read item %v from array", x)
-				}
+	capacity := length
+	if capacity > maxAggregateCapacity {
+		capacity = maxAggregateCapacity
+	}
 
-				contents = append(contents, result.content)
-			}
+	contents := make([]interface{}, 0, capacity)
 
-			return &Result{
-				content: contents,
-			}, nil
+	for x := int64(0); x < length; x++ {
+		result, err := readRESP(r)
+		if err != nil {
+			return nil, pkgerrors.Wrapf(err, "failed to read item %v from array", x)
 		}
+
+		contents = append(contents, result.content)
+	}
+
+	return contents, nil
+}
+
+// readLength parses the length of types that can't be null like `%2`, `~3` or `>4`.
+func readLength(line []byte) (int64, error) {
+	length, err := parseLength(line)
+	if err != nil {
+		return 0, err
 	}
 
-	if r.Err() == nil {
-		return nil, errors.New("scanner was empty")
+	if length < 0 {
+		return 0, fmt.Errorf("invalid length: %v", string(line))
 	}
 
-	return nil, r.Err()
+	return length, nil
 }
 
-var (
-	handlers = map[int32]func(*bufio.Scanner, string) (*Result, error){
-		typeSimpleString: func(r *bufio.Scanner, s string) (*Result, error) {
-			return &Result{
-				content: s[1:],
-			}, nil
-		},
-		typeBulkString: func(r *bufio.Scanner, s string) (*Result, error) {
-			return &Result{
-				content: nil,
-			}, nil
-		},
-		typeErorr: func(r *bufio.Scanner, s string) (*Result, error) {
-			return &Result{
-				content: errors.New(s[1:]),
-			}, nil
-		},
-		typeInteger: func(r *bufio.Scanner, line string) (*Result, error) {
-			content, err := strconv.ParseInt(line[1:], 10, 64)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse returned integer: %v (value: %v)", err, line)
-			}
-			return &Result{
-				content: content,
-			}, nil
-		},
-		typeArray: func(r *bufio.Scanner, line string) (*Result, error) {
-			// the first thing to be done when we find an array is to find its length, if not `-1` we then
-			// read items from the scanner until we've read all items on the array.
-			length, err := strconv.ParseInt(line[1:], 10, 64)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse array length: %v (value: %v)", err, line)
-			}
+func readDouble(line []byte) (*Result, error) {
+	// strconv handles the `inf`, `-inf` and `nan` values redis sends for special doubles
+	content, err := strconv.ParseFloat(string(line[1:]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse returned double: %v (value: %v)", err, string(line))
+	}
 
-			if length == -1 {
-				return &Result{content: nil}, nil
-			}
+	return &Result{
+		content: content,
+	}, nil
+}
 
-			contents := make([]interface{}, 0, length)
+func readBoolean(line []byte) (*Result, error) {
+	switch string(line[1:]) {
+	case "t":
+		return &Result{content: true}, nil
+	case "f":
+		return &Result{content: false}, nil
+	}
 
-			for x := int64(0); x < length; x++ {
-				result, err := readRESP(r)
-				if err != nil {
-					return nil, pkgerrors.Wrapf(err, "failed to read item %v from array", x)
-				}
+	return nil, fmt.Errorf("failed to parse returned boolean, value should be t or f: %v", string(line))
+}
 
-				contents = append(contents, result.content)
-			}
+func readBigNumber(line []byte) (*Result, error) {
+	content, ok := new(big.Int).SetString(string(line[1:]), 10)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse returned big number: %v", string(line))
+	}
 
-			return &Result{
-				content: contents,
-			}, nil
+	return &Result{
+		content: content,
+	}, nil
+}
+
+func readVerbatimString(content string) (*Result, error) {
+	// verbatim strings always start with a 3 character format and a `:` like `txt:Some string`
+	if len(content) < 4 || content[3] != ':' {
+		return nil, fmt.Errorf("failed to parse returned verbatim string, it must start with a format like `txt:`: %v", content)
+	}
+
+	return &Result{
+		content: Verbatim{
+			Format: content[:3],
+			Text:   content[4:],
 		},
+	}, nil
+}
+
+func readSet(r *Reader, line []byte) (*Result, error) {
+	length, err := readLength(line)
+	if err != nil {
+		return nil, err
 	}
-)
 
-func readRESPMap(r *bufio.Scanner) (*Result, error) {
-	for r.Scan() {
-		line := r.Text()
-		handler := handlers[int32(line[0])]
-		return handler(r, line)
+	contents, err := readItems(r, length)
+	if err != nil {
+		return nil, err
 	}
 
-	if r.Err() == nil {
-		return nil, errors.New("scanner was empty")
+	return &Result{
+		content: Set(contents),
+	}, nil
+}
+
+func readPush(r *Reader, line []byte) (*Result, error) {
+	length, err := readLength(line)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, r.Err()
+	contents, err := readItems(r, length)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		content: Push(contents),
+	}, nil
+}
+
+func readMap(r *Reader, line []byte) (*Result, error) {
+	length, err := readLength(line)
+	if err != nil {
+		return nil, err
+	}
+
+	// every entry is a key and a value, so the number of items would overflow past half the max
+	if length > maxAggregateLength/2 {
+		return nil, fmt.Errorf("map of %v entries is bigger than the max length of %v entries", length, maxAggregateLength/2)
+	}
+
+	contents, err := readItems(r, length*2)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(Map, 0, len(contents)/2)
+	for x := 0; x < len(contents); x += 2 {
+		entries = append(entries, MapEntry{
+			Key:   contents[x],
+			Value: contents[x+1],
+		})
+	}
+
+	return &Result{
+		content: entries,
+	}, nil
+}
+
+// readAttribute reads an attribute map and then the reply it belongs to. attributes are only kept
+// for the top level reply, attributes sent for items inside aggregates are dropped.
+func readAttribute(r *Reader, line []byte) (*Result, error) {
+	attributes, err := readMap(r, line)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "failed to read attributes")
+	}
+
+	result, err := readRESP(r)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "failed to read reply after attributes")
+	}
+
+	result.attributes = attributes.content.(Map)
+
+	return result, nil
 }
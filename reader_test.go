@@ -1,9 +1,15 @@
 package redis_client
 
 import (
-	"errors"
+	"bytes"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
+	"math"
+	"math/big"
+	"runtime"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -20,7 +26,7 @@ func TestReader_Read(t *testing.T) {
 		},
 		{
 			input:  "-ERR unknown command 'foobar'\r\n",
-			result: errors.New("ERR unknown command 'foobar'"),
+			result: &RedisError{Prefix: "ERR", Message: "unknown command 'foobar'"},
 		},
 		{
 			input:  ":1000\r\n",
@@ -28,7 +34,11 @@ func TestReader_Read(t *testing.T) {
 		},
 		{
 			input: "+\r",
-			err:   "unexpected end of stream, a redis message needs at least 3 characters to be valid, actual content in base64: [Kw0]",
+			err:   "unexpected end of stream, there should have been a \\r\\n before the end, actual content in base64: [Kw0]",
+		},
+		{
+			input: "+\n",
+			err:   "invalid line, a redis message needs a type and to end with \\r\\n, actual content in base64: [Kwo]",
 		},
 		{
 			input: "+BROKEN\r",
@@ -79,10 +89,79 @@ func TestReader_Read(t *testing.T) {
 				},
 				[]interface{}{
 					"Foo",
-					errors.New("Bar"),
+					&RedisError{Message: "Bar"},
 				},
 			},
 		},
+		{
+			input:  "_\r\n",
+			result: nil,
+		},
+		{
+			input:  ",3.14\r\n",
+			result: 3.14,
+		},
+		{
+			input:  ",-inf\r\n",
+			result: math.Inf(-1),
+		},
+		{
+			input:  "#t\r\n",
+			result: true,
+		},
+		{
+			input:  "#f\r\n",
+			result: false,
+		},
+		{
+			input: "#x\r\n",
+			err:   "failed to parse returned boolean, value should be t or f: #x",
+		},
+		{
+			input:  "(3492890328409238509324850943850943825024385\r\n",
+			result: bigInt("3492890328409238509324850943850943825024385"),
+		},
+		{
+			input:  "!21\r\nSYNTAX invalid syntax\r\n",
+			result: &RedisError{Prefix: "SYNTAX", Message: "invalid syntax"},
+		},
+		{
+			input: "=15\r\ntxt:Some string\r\n",
+			result: Verbatim{
+				Format: "txt",
+				Text:   "Some string",
+			},
+		},
+		{
+			input: "=11\r\ntxt:a\r\nb\r\nc\r\n",
+			result: Verbatim{
+				Format: "txt",
+				Text:   "a\r\nb\r\nc",
+			},
+		},
+		{
+			input: "%2\r\n+first\r\n:1\r\n$6\r\nsecond\r\n*2\r\n:2\r\n:3\r\n",
+			result: Map{
+				{Key: "first", Value: int64(1)},
+				{Key: "second", Value: []interface{}{int64(2), int64(3)}},
+			},
+		},
+		{
+			input:  "~3\r\n+orange\r\n+apple\r\n#t\r\n",
+			result: Set{"orange", "apple", true},
+		},
+		{
+			input:  ">3\r\n$7\r\nmessage\r\n$7\r\nchannel\r\n$7\r\npayload\r\n",
+			result: Push{"message", "channel", "payload"},
+		},
+		{
+			input:  "|1\r\n+key-popularity\r\n%1\r\n$1\r\na\r\n,0.1923\r\n*2\r\n:2039123\r\n:9543892\r\n",
+			result: []interface{}{int64(2039123), int64(9543892)},
+		},
+		{
+			input: "?what\r\n",
+			err:   "unknown RESP type '?', actual content in base64: [P3doYXQ]",
+		},
 	}
 
 	for _, ts := range tt {
@@ -96,26 +175,272 @@ func TestReader_Read(t *testing.T) {
 				assert.Equal(t, ts.result, result.Content())
 			}
 
-			assert.False(t, r.scanner.Scan())
+			_, err = r.Read()
+			assert.Equal(t, io.EOF, err)
 		})
 	}
 
 }
 
+func TestReader_ReadLargeValues(t *testing.T) {
+	value := strings.Repeat("a", 1024*1024)
+	input := fmt.Sprintf("*2\r\n$%v\r\n%v\r\n+%v\r\n", len(value), value, value)
+
+	r := NewReader(strings.NewReader(input))
+	result, err := r.Read()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{value, value}, result.Content())
+}
+
+func TestReader_ReadLimit(t *testing.T) {
+	r := NewReaderLimit(strings.NewReader("$10\r\n0123456789\r\n"), 5)
+	_, err := r.Read()
+	assert.EqualError(t, err, "bulk string of 10 bytes is bigger than the max length of 5 bytes")
+}
+
+func TestReader_ReadAggregateLimits(t *testing.T) {
+	tt := []struct {
+		input string
+		err   string
+	}{
+		{
+			input: "*9223372036854775807\r\n",
+			err:   "aggregate of 9223372036854775807 items is bigger than the max length of 2147483647 items",
+		},
+		{
+			input: "~2147483648\r\n",
+			err:   "aggregate of 2147483648 items is bigger than the max length of 2147483647 items",
+		},
+		{
+			input: ">9223372036854775807\r\n",
+			err:   "aggregate of 9223372036854775807 items is bigger than the max length of 2147483647 items",
+		},
+		{
+			input: "%9223372036854775807\r\n",
+			err:   "map of 9223372036854775807 entries is bigger than the max length of 1073741823 entries",
+		},
+		{
+			input: "%4611686018427387904\r\n",
+			err:   "map of 4611686018427387904 entries is bigger than the max length of 1073741823 entries",
+		},
+		{
+			input: "|9223372036854775807\r\n",
+			err:   "failed to read attributes: map of 9223372036854775807 entries is bigger than the max length of 1073741823 entries",
+		},
+		{
+			// a valid length that is never followed by the items doesn't allocate memory for all of them
+			input: "*2147483647\r\n:1\r\n",
+			err:   "EOF",
+		},
+		{
+			input: "%1073741823\r\n+key\r\n",
+			err:   "EOF",
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.input, func(t *testing.T) {
+			_, err := NewReader(strings.NewReader(ts.input)).Read()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), ts.err)
+		})
+	}
+}
+
+func TestReader_ReadBulkTo(t *testing.T) {
+	tt := []struct {
+		name    string
+		input   string
+		output  string
+		written int64
+		err     string
+	}{
+		{
+			name:    "a bulk string",
+			input:   "$13\r\nfoo\r\nbar\r\nbaz\r\n+OK\r\n",
+			output:  "foo\r\nbar\r\nbaz",
+			written: 13,
+		},
+		{
+			name:  "a nil string",
+			input: "$-1\r\n+OK\r\n",
+			err:   "redis: nil",
+		},
+		{
+			name:  "an error",
+			input: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n+OK\r\n",
+			err:   "WRONGTYPE Operation against a key holding the wrong kind of value",
+		},
+		{
+			name:  "an array",
+			input: "*2\r\n:1\r\n:2\r\n+OK\r\n",
+			err:   "reply is not a bulk string: []interface {}{1, 2}",
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			r := NewReaderLimit(strings.NewReader(ts.input), 1)
+			buffer := &bytes.Buffer{}
+
+			written, err := r.ReadBulkTo(buffer)
+			if ts.err != "" {
+				assert.EqualError(t, err, ts.err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, ts.written, written)
+			assert.Equal(t, ts.output, buffer.String())
+
+			// the stream must still be usable after the bulk string
+			result, err := r.Read()
+			require.NoError(t, err)
+			assert.Equal(t, "OK", result.Content())
+		})
+	}
+}
+
+func TestReader_ReadBulkGrowsWithData(t *testing.T) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	// a length just under the default limit that is never followed by the data
+	_, err := NewReader(strings.NewReader("$536870911\r\nabc")).Read()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	runtime.ReadMemStats(&after)
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(64*1024*1024))
+
+	value := strings.Repeat("a", maxBulkCapacity*3)
+	result, err := NewReader(strings.NewReader("$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n")).Read()
+	require.NoError(t, err)
+	assert.Equal(t, value, result.Content())
+}
+
+func TestReader_ReadAttributes(t *testing.T) {
+	r := NewReader(strings.NewReader("|1\r\n+ttl\r\n:3600\r\n+OK\r\n"))
+	result, err := r.Read()
+	require.NoError(t, err)
+
+	assert.Equal(t, "OK", result.Content())
+	assert.Equal(t, Map{{Key: "ttl", Value: int64(3600)}}, result.Attributes())
+}
+
+func bigInt(value string) *big.Int {
+	result, _ := new(big.Int).SetString(value, 10)
+	return result
+}
+
 func BenchmarkReadRESP(b *testing.B) {
 	value := "*2\r\n*3\r\n:1\r\n:2\r\n:3\r\n*2\r\n+Foo\r\n-Bar\r\n"
 	// run the Fib function b.N times
 	for n := 0; n < b.N; n++ {
 		reader := NewReader(strings.NewReader(value))
-		readRESP(reader.scanner)
+		readRESP(reader)
 	}
 }
 
-func BenchmarkReadRESPMap(b *testing.B) {
-	value := "*2\r\n*3\r\n:1\r\n:2\r\n:3\r\n*2\r\n+Foo\r\n-Bar\r\n"
-	// run the Fib function b.N times
+// repeatingReader returns the same content forever so readers can be benchmarked without being created
+// on every iteration.
+type repeatingReader struct {
+	content []byte
+	offset  int
+}
+
+func (r *repeatingReader) Read(p []byte) (int, error) {
+	n := copy(p, r.content[r.offset:])
+	r.offset = (r.offset + n) % len(r.content)
+	return n, nil
+}
+
+func BenchmarkReadRESPReused(b *testing.B) {
+	reader := NewReader(&repeatingReader{content: []byte("*2\r\n*3\r\n:1\r\n:2\r\n:3\r\n*2\r\n+Foo\r\n-Bar\r\n")})
+
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
-		reader := NewReader(strings.NewReader(value))
-		readRESPMap(reader.scanner)
+		readRESP(reader)
+	}
+}
+
+func BenchmarkNextToken(b *testing.B) {
+	reader := NewReader(&repeatingReader{content: []byte("*2\r\n*3\r\n:1\r\n:2\r\n:3\r\n*2\r\n+Foo\r\n-Bar\r\n")})
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		// the same 8 tokens readRESP reads as a single reply
+		for x := 0; x < 8; x++ {
+			reader.NextToken()
+		}
+	}
+}
+
+func mgetReply(size int) []byte {
+	buffer := &bytes.Buffer{}
+	writer := NewWriter(buffer)
+
+	values := make([]interface{}, 0, size)
+	for x := 0; x < size; x++ {
+		values = append(values, "value-"+strconv.Itoa(x))
+	}
+
+	writer.WriteArray(values)
+	writer.Flush()
+
+	return buffer.Bytes()
+}
+
+func BenchmarkReadRESPMGET(b *testing.B) {
+	reader := NewReader(&repeatingReader{content: mgetReply(100)})
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		result, _ := readRESP(reader)
+		result.StringSlice()
+	}
+}
+
+func BenchmarkNextTokenMGET(b *testing.B) {
+	reader := NewReader(&repeatingReader{content: mgetReply(100)})
+	values := make([][]byte, 0, 100)
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		values, _ = decodeMGET(reader, values)
+	}
+}
+
+func BenchmarkWriteCommand(b *testing.B) {
+	writer := NewWriter(io.Discard)
+	args := []interface{}{"SET", "some-key", []byte("some-value")}
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		writer.WriteCommand(args...)
+		writer.Flush()
+	}
+}
+
+func BenchmarkWriteArray(b *testing.B) {
+	writer := NewWriter(io.Discard)
+	values := []interface{}{"SET", "some-key", "some-value", int64(10)}
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		writer.WriteArray(values)
+		writer.Flush()
+	}
+}
+
+func BenchmarkWriteCommandLargeValue(b *testing.B) {
+	writer := NewWriter(io.Discard)
+	value := bytes.Repeat([]byte("a"), 1024*1024)
+	args := []interface{}{"SET", "some-key", value}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(value)))
+	for n := 0; n < b.N; n++ {
+		writer.WriteCommand(args...)
+		writer.Flush()
 	}
 }
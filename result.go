@@ -1,9 +1,51 @@
 package redis_client
 
-import "fmt"
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNil is returned when the server replies with a null value.
+	ErrNil = errors.New("redis: nil")
+	// ErrNoExpiration is returned by Duration when TTL or PTTL reply with -1, the key exists but has no expiration.
+	ErrNoExpiration = errors.New("redis: key has no expiration")
+	// ErrNoKey is returned by Duration when TTL or PTTL reply with -2, the key does not exist.
+	ErrNoKey = errors.New("redis: key does not exist")
+)
+
+// Map is the content of a RESP3 map reply. entries are kept in the order the server sent them
+// as keys can be any redis type and not all of them can be keys in a Go map.
+type Map []MapEntry
+
+// MapEntry is a single key and value pair inside a Map.
+type MapEntry struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// Set is the content of a RESP3 set reply.
+type Set []interface{}
+
+// Push is the content of a RESP3 push frame, these are out of band messages like pub/sub messages
+// or client side caching invalidations.
+type Push []interface{}
+
+// Verbatim is the content of a RESP3 verbatim string, Format is the 3 characters format
+// (like `txt` or `mkd`) and Text is the actual string.
+type Verbatim struct {
+	Format string
+	Text   string
+}
 
 type Result struct {
-	content interface{}
+	content    interface{}
+	attributes Map
 }
 
 func (r *Result) Err() error {
@@ -15,17 +57,135 @@ func (r *Result) Err() error {
 	return err
 }
 
+// Int64 returns integer replies and strings holding integers, like GET on a key changed with INCR.
 func (r *Result) Int64() (int64, error) {
-	if err := r.Err(); err != nil {
+	return int64Value(r.content)
+}
+
+// Uint64 returns integer replies that are not negative, big numbers that fit an uint64 and strings holding
+// unsigned integers.
+func (r *Result) Uint64() (uint64, error) {
+	switch t := r.content.(type) {
+	case error:
+		return 0, t
+	case nil:
+		return 0, ErrNil
+	case int64:
+		if t < 0 {
+			return 0, fmt.Errorf("content is a negative integer: %v", t)
+		}
+
+		return uint64(t), nil
+	case *big.Int:
+		if !t.IsUint64() {
+			return 0, fmt.Errorf("content does not fit an uint64: %v", t)
+		}
+
+		return t.Uint64(), nil
+	case string:
+		result, err := strconv.ParseUint(t, 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to parse %#v as an uint64", t)
+		}
+
+		return result, nil
+	}
+
+	return 0, fmt.Errorf("content is not an uint64: %#v", r.content)
+}
+
+// Float64 returns RESP3 doubles, integers and strings holding numbers, like the replies for INCRBYFLOAT or
+// ZSCORE on RESP2. inf and -inf are parsed as infinities.
+func (r *Result) Float64() (float64, error) {
+	switch t := r.content.(type) {
+	case error:
+		return 0, t
+	case nil:
+		return 0, ErrNil
+	case float64:
+		return t, nil
+	case int64:
+		return float64(t), nil
+	case string:
+		result, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to parse %#v as a float64", t)
+		}
+
+		return result, nil
+	}
+
+	return 0, fmt.Errorf("content is not a float64: %#v", r.content)
+}
+
+// Bool returns RESP3 booleans and integers as booleans, 0 is false and any other integer is true, which is
+// how commands like EXISTS or SISMEMBER reply on RESP2.
+func (r *Result) Bool() (bool, error) {
+	switch t := r.content.(type) {
+	case error:
+		return false, t
+	case nil:
+		return false, ErrNil
+	case bool:
+		return t, nil
+	case int64:
+		return t != 0, nil
+	}
+
+	return false, fmt.Errorf("content is not a bool: %#v", r.content)
+}
+
+// Bytes returns a string reply as a byte slice, it returns ErrNil if the reply is null.
+func (r *Result) Bytes() ([]byte, error) {
+	value, err := stringValue(r.content)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(value), nil
+}
+
+// Duration returns TTL replies as durations, unit is the unit of the reply, time.Second for TTL and
+// time.Millisecond for PTTL. it returns ErrNoExpiration if the key has no expiration and ErrNoKey if the
+// key does not exist.
+func (r *Result) Duration(unit time.Duration) (time.Duration, error) {
+	value, err := int64Value(r.content)
+	if err != nil {
 		return 0, err
 	}
 
-	result, ok := r.content.(int64)
-	if !ok {
-		return 0, fmt.Errorf("content is not an int64: %#v", r.content)
+	switch value {
+	case -1:
+		return 0, ErrNoExpiration
+	case -2:
+		return 0, ErrNoKey
 	}
 
-	return result, nil
+	return time.Duration(value) * unit, nil
+}
+
+// Time returns the reply for TIME, an array with the unix time in seconds and the microseconds.
+func (r *Result) Time() (time.Time, error) {
+	values, err := r.StringSlice()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if len(values) != 2 {
+		return time.Time{}, fmt.Errorf("time reply should have 2 elements but has %v: %#v", len(values), values)
+	}
+
+	seconds, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "failed to parse seconds %#v", values[0])
+	}
+
+	microseconds, err := strconv.ParseInt(values[1], 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "failed to parse microseconds %#v", values[1])
+	}
+
+	return time.Unix(seconds, microseconds*int64(time.Microsecond)), nil
 }
 
 func (r *Result) String() (string, bool, error) {
@@ -54,14 +214,200 @@ func (r *Result) Slice() ([]interface{}, error) {
 		return nil, nil
 	}
 
-	result, ok := r.content.([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("content is not a slice: %#v", r.content)
+	switch t := r.content.(type) {
+	case []interface{}:
+		return t, nil
+	case Set:
+		return t, nil
+	case Push:
+		return t, nil
+	}
+
+	return nil, fmt.Errorf("content is not a slice: %#v", r.content)
+}
+
+// Len returns the number of elements on an array, set or push reply and 0 for any other reply.
+func (r *Result) Len() int {
+	values, err := r.Slice()
+	if err != nil {
+		return 0
+	}
+
+	return len(values)
+}
+
+// Index returns the element at i on an array, set or push reply as a Result of its own, so errors nested
+// inside arrays, like the reply of a failed command inside EXEC, are seen with Err on the element.
+// if the reply is not an array or i is out of range the returned Result holds the error.
+func (r *Result) Index(i int) *Result {
+	values, err := r.Slice()
+	if err != nil {
+		return &Result{content: err}
+	}
+
+	if i < 0 || i >= len(values) {
+		return &Result{content: fmt.Errorf("index %v out of range, reply has %v elements", i, len(values))}
+	}
+
+	return &Result{content: values[i]}
+}
+
+// Results returns the elements of an array, set or push reply as Results.
+func (r *Result) Results() ([]*Result, error) {
+	values, err := r.Slice()
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	if values == nil {
+		return nil, nil
+	}
+
+	results := make([]*Result, 0, len(values))
+	for _, value := range values {
+		results = append(results, &Result{content: value})
+	}
+
+	return results, nil
+}
+
+// StringSlice returns an array reply where every element is a string, it returns ErrNil if the reply is null.
+func (r *Result) StringSlice() ([]string, error) {
+	values, err := r.Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	if values == nil {
+		return nil, ErrNil
+	}
+
+	results := make([]string, 0, len(values))
+	for x, value := range values {
+		result, err := stringValue(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "element %v is not a string", x)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// Int64Slice returns an array reply where every element is an integer, it returns ErrNil if the reply is null.
+func (r *Result) Int64Slice() ([]int64, error) {
+	values, err := r.Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	if values == nil {
+		return nil, ErrNil
+	}
+
+	results := make([]int64, 0, len(values))
+	for x, value := range values {
+		result, err := int64Value(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "element %v is not an int64", x)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// StringMap returns a RESP3 map or a RESP2 array of keys and values, like the replies for HGETALL or
+// CONFIG GET, as a map of strings. it returns ErrNil if the reply is null.
+func (r *Result) StringMap() (map[string]string, error) {
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	if r.content == nil {
+		return nil, ErrNil
+	}
+
+	entries, err := pairs(r.content)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		key, err := stringValue(entry.Key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "key %#v is not a string", entry.Key)
+		}
+
+		value, err := stringValue(entry.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "value for key %v is not a string", key)
+		}
+
+		results[key] = value
+	}
+
+	return results, nil
 }
 
 func (r *Result) Content() interface{} {
 	return r.content
 }
+
+// Attributes returns the RESP3 attributes the server sent before this reply, if any.
+func (r *Result) Attributes() Map {
+	return r.attributes
+}
+
+// Reader returns an io.Reader over a string reply so it can be handed to code that works with streams.
+// to stream values that should not be buffered in memory at all use Reader.ReadBulkTo instead.
+func (r *Result) Reader() (io.Reader, error) {
+	value, isNil, err := r.String()
+	if err != nil {
+		return nil, err
+	}
+
+	if isNil {
+		return nil, ErrNil
+	}
+
+	return strings.NewReader(value), nil
+}
+
+func int64Value(content interface{}) (int64, error) {
+	switch t := content.(type) {
+	case error:
+		return 0, t
+	case nil:
+		return 0, ErrNil
+	case int64:
+		return t, nil
+	case string:
+		result, err := strconv.ParseInt(t, 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to parse %#v as an int64", t)
+		}
+
+		return result, nil
+	}
+
+	return 0, fmt.Errorf("content is not an int64: %#v", content)
+}
+
+func stringValue(content interface{}) (string, error) {
+	switch t := content.(type) {
+	case error:
+		return "", t
+	case nil:
+		return "", ErrNil
+	case string:
+		return t, nil
+	case Verbatim:
+		return t.Text, nil
+	}
+
+	return "", fmt.Errorf("content is not a string: %#v", content)
+}
@@ -4,7 +4,10 @@ import (
 	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"math"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestResult_Int64(t *testing.T) {
@@ -20,9 +23,24 @@ func TestResult_Int64(t *testing.T) {
 			result: 10,
 		},
 		{
-			name:  "a string instead of an int64",
+			name:   "a string holding an int64",
+			input:  "-10",
+			result: -10,
+		},
+		{
+			name:  "a string that is not an int64",
 			input: "some string",
-			err:   "content is not an int64: \"some string\"",
+			err:   "failed to parse \"some string\" as an int64: strconv.ParseInt: parsing \"some string\": invalid syntax",
+		},
+		{
+			name:  "a double instead of an int64",
+			input: 1.5,
+			err:   "content is not an int64: 1.5",
+		},
+		{
+			name:  "a nil int64",
+			input: nil,
+			err:   "redis: nil",
 		},
 		{
 			name:  "an error instead of an int64",
@@ -154,3 +172,460 @@ func TestResult_Slice(t *testing.T) {
 		})
 	}
 }
+
+func TestResult_Index(t *testing.T) {
+	r := &Result{
+		content: []interface{}{
+			"OK",
+			&RedisError{Prefix: PrefixWrongType, Message: "Operation against a key holding the wrong kind of value"},
+			[]interface{}{int64(1), int64(2)},
+		},
+	}
+
+	assert.Equal(t, 3, r.Len())
+
+	value, _, err := r.Index(0).String()
+	require.NoError(t, err)
+	assert.Equal(t, "OK", value)
+
+	assert.True(t, HasPrefix(r.Index(1).Err(), PrefixWrongType))
+
+	number, err := r.Index(2).Index(1).Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), number)
+
+	assert.EqualError(t, r.Index(3).Err(), "index 3 out of range, reply has 3 elements")
+	assert.EqualError(t, r.Index(0).Index(0).Err(), "content is not a slice: \"OK\"")
+	assert.Equal(t, 0, r.Index(0).Len())
+}
+
+func TestResult_Results(t *testing.T) {
+	tt := []struct {
+		name   string
+		input  interface{}
+		result []*Result
+		err    string
+	}{
+		{
+			name:  "an array with an error",
+			input: []interface{}{"foo", errors.New("damn")},
+			result: []*Result{
+				{content: "foo"},
+				{content: errors.New("damn")},
+			},
+		},
+		{
+			name:   "a set",
+			input:  Set{int64(1)},
+			result: []*Result{{content: int64(1)}},
+		},
+		{
+			name:  "a nil slice",
+			input: nil,
+		},
+		{
+			name:  "an error instead of an slice",
+			input: errors.New("damn"),
+			err:   "damn",
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			r := &Result{
+				content: ts.input,
+			}
+
+			results, err := r.Results()
+
+			if ts.err != "" {
+				assert.EqualError(t, err, ts.err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, ts.result, results)
+		})
+	}
+}
+
+func TestResult_Uint64(t *testing.T) {
+	tt := []struct {
+		name   string
+		input  interface{}
+		result uint64
+		err    string
+	}{
+		{
+			name:   "an int64",
+			input:  int64(10),
+			result: 10,
+		},
+		{
+			name:   "a big number",
+			input:  bigInt("18446744073709551615"),
+			result: math.MaxUint64,
+		},
+		{
+			name:   "a string",
+			input:  "18446744073709551615",
+			result: math.MaxUint64,
+		},
+		{
+			name:  "a negative int64",
+			input: int64(-1),
+			err:   "content is a negative integer: -1",
+		},
+		{
+			name:  "a big number that is too big",
+			input: bigInt("18446744073709551616"),
+			err:   "content does not fit an uint64: 18446744073709551616",
+		},
+		{
+			name:  "nil",
+			input: nil,
+			err:   "redis: nil",
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			value, err := (&Result{content: ts.input}).Uint64()
+
+			if ts.err != "" {
+				assert.EqualError(t, err, ts.err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, ts.result, value)
+		})
+	}
+}
+
+func TestResult_IntegersFromStrings(t *testing.T) {
+	tt := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "a simple string",
+			input: "+42\r\n",
+		},
+		{
+			name:  "a bulk string",
+			input: "$2\r\n42\r\n",
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			result, err := NewReader(strings.NewReader(ts.input)).Read()
+			require.NoError(t, err)
+
+			signed, err := result.Int64()
+			require.NoError(t, err)
+			assert.Equal(t, int64(42), signed)
+
+			unsigned, err := result.Uint64()
+			require.NoError(t, err)
+			assert.Equal(t, uint64(42), unsigned)
+		})
+	}
+}
+
+func TestResult_Float64(t *testing.T) {
+	tt := []struct {
+		name   string
+		input  interface{}
+		result float64
+		err    string
+	}{
+		{
+			name:   "a double",
+			input:  3.14,
+			result: 3.14,
+		},
+		{
+			name:   "an int64",
+			input:  int64(10),
+			result: 10,
+		},
+		{
+			name:   "a string",
+			input:  "10.5",
+			result: 10.5,
+		},
+		{
+			name:   "infinity",
+			input:  "-inf",
+			result: math.Inf(-1),
+		},
+		{
+			name:  "a string that is not a number",
+			input: "ten",
+			err:   "failed to parse \"ten\" as a float64: strconv.ParseFloat: parsing \"ten\": invalid syntax",
+		},
+		{
+			name:  "nil",
+			input: nil,
+			err:   "redis: nil",
+		},
+		{
+			name:  "an error",
+			input: errors.New("damn"),
+			err:   "damn",
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			value, err := (&Result{content: ts.input}).Float64()
+
+			if ts.err != "" {
+				assert.EqualError(t, err, ts.err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, ts.result, value)
+		})
+	}
+}
+
+func TestResult_Bool(t *testing.T) {
+	tt := []struct {
+		name   string
+		input  interface{}
+		result bool
+		err    string
+	}{
+		{
+			name:   "a boolean",
+			input:  true,
+			result: true,
+		},
+		{
+			name:   "a one",
+			input:  int64(1),
+			result: true,
+		},
+		{
+			name:   "a zero",
+			input:  int64(0),
+			result: false,
+		},
+		{
+			name:  "a string",
+			input: "true",
+			err:   "content is not a bool: \"true\"",
+		},
+		{
+			name:  "nil",
+			input: nil,
+			err:   "redis: nil",
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			value, err := (&Result{content: ts.input}).Bool()
+
+			if ts.err != "" {
+				assert.EqualError(t, err, ts.err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, ts.result, value)
+		})
+	}
+}
+
+func TestResult_Bytes(t *testing.T) {
+	value, err := (&Result{content: "some value"}).Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("some value"), value)
+
+	value, err = (&Result{content: Verbatim{Format: "txt", Text: "some text"}}).Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("some text"), value)
+
+	_, err = (&Result{}).Bytes()
+	assert.Equal(t, ErrNil, err)
+}
+
+func TestResult_StringSlice(t *testing.T) {
+	tt := []struct {
+		name   string
+		input  interface{}
+		result []string
+		err    string
+	}{
+		{
+			name:   "an array of strings",
+			input:  []interface{}{"foo", "bar"},
+			result: []string{"foo", "bar"},
+		},
+		{
+			name:   "a set of strings",
+			input:  Set{"foo"},
+			result: []string{"foo"},
+		},
+		{
+			name:  "an array with a nil",
+			input: []interface{}{"foo", nil},
+			err:   "element 1 is not a string: redis: nil",
+		},
+		{
+			name:  "an array with an integer",
+			input: []interface{}{int64(1)},
+			err:   "element 0 is not a string: content is not a string: 1",
+		},
+		{
+			name:  "nil",
+			input: nil,
+			err:   "redis: nil",
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			value, err := (&Result{content: ts.input}).StringSlice()
+
+			if ts.err != "" {
+				assert.EqualError(t, err, ts.err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, ts.result, value)
+		})
+	}
+}
+
+func TestResult_Int64Slice(t *testing.T) {
+	value, err := (&Result{content: []interface{}{int64(1), int64(0)}}).Int64Slice()
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 0}, value)
+
+	value, err = (&Result{content: []interface{}{int64(1), "2"}}).Int64Slice()
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, value)
+
+	_, err = (&Result{content: []interface{}{int64(1), "two"}}).Int64Slice()
+	assert.EqualError(t, err, "element 1 is not an int64: failed to parse \"two\" as an int64: strconv.ParseInt: parsing \"two\": invalid syntax")
+
+	_, err = (&Result{}).Int64Slice()
+	assert.Equal(t, ErrNil, err)
+}
+
+func TestResult_StringMap(t *testing.T) {
+	tt := []struct {
+		name   string
+		input  interface{}
+		result map[string]string
+		err    string
+	}{
+		{
+			name:   "a RESP2 array",
+			input:  []interface{}{"name", "joe", "age", "30"},
+			result: map[string]string{"name": "joe", "age": "30"},
+		},
+		{
+			name:   "a RESP3 map",
+			input:  Map{{Key: "name", Value: "joe"}},
+			result: map[string]string{"name": "joe"},
+		},
+		{
+			name:  "an odd array",
+			input: []interface{}{"name"},
+			err:   "array has an odd number of items (1) and can't be read as keys and values",
+		},
+		{
+			name:  "a map with integer values",
+			input: Map{{Key: "age", Value: int64(30)}},
+			err:   "value for key age is not a string: content is not a string: 30",
+		},
+		{
+			name:  "nil",
+			input: nil,
+			err:   "redis: nil",
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			value, err := (&Result{content: ts.input}).StringMap()
+
+			if ts.err != "" {
+				assert.EqualError(t, err, ts.err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, ts.result, value)
+		})
+	}
+}
+
+func TestResult_Duration(t *testing.T) {
+	tt := []struct {
+		name   string
+		input  interface{}
+		unit   time.Duration
+		result time.Duration
+		err    error
+	}{
+		{
+			name:   "seconds",
+			input:  int64(10),
+			unit:   time.Second,
+			result: time.Second * 10,
+		},
+		{
+			name:   "milliseconds",
+			input:  int64(1500),
+			unit:   time.Millisecond,
+			result: time.Millisecond * 1500,
+		},
+		{
+			name:  "no expiration",
+			input: int64(-1),
+			unit:  time.Second,
+			err:   ErrNoExpiration,
+		},
+		{
+			name:  "no key",
+			input: int64(-2),
+			unit:  time.Second,
+			err:   ErrNoKey,
+		},
+		{
+			name:  "nil",
+			input: nil,
+			unit:  time.Second,
+			err:   ErrNil,
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			value, err := (&Result{content: ts.input}).Duration(ts.unit)
+			assert.Equal(t, ts.err, err)
+			assert.Equal(t, ts.result, value)
+		})
+	}
+}
+
+func TestResult_Time(t *testing.T) {
+	value, err := (&Result{content: []interface{}{"1700000000", "250000"}}).Time()
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1700000000, int64(time.Millisecond*250)), value)
+
+	_, err = (&Result{content: []interface{}{"1700000000"}}).Time()
+	assert.EqualError(t, err, "time reply should have 2 elements but has 1: []string{\"1700000000\"}")
+
+	_, err = (&Result{}).Time()
+	assert.Equal(t, ErrNil, err)
+}
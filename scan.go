@@ -0,0 +1,292 @@
+package redis_client
+
+import (
+	"encoding"
+	"fmt"
+	"github.com/pkg/errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// structTag is the struct tag with the redis field name for a struct field
+	structTag = "redis"
+)
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+	// structFieldsCache keeps the fields for each struct type so tags are only parsed once
+	structFieldsCache sync.Map
+)
+
+// structField is a struct field with a `redis` tag.
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// structInfo has the tagged fields of a struct type in the order they are declared and by name.
+type structInfo struct {
+	fields []structField
+	byName map[string]structField
+}
+
+// Scan decodes a RESP3 map or a RESP2 array of field and value pairs, like the reply for HGETALL, into the
+// struct dst points to. only fields tagged with `redis:"name"` are set, fields missing from the reply, fields
+// with null values and fields in the reply that are not on the struct are ignored.
+//
+// fields can be strings, []byte, ints, uints, floats, bools or any type implementing encoding.TextUnmarshaler,
+// which includes time.Time as RFC 3339 timestamps.
+func (r *Result) Scan(dst interface{}) error {
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	if r.content == nil {
+		return ErrNil
+	}
+
+	value := reflect.ValueOf(dst)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("scan destination must be a non nil pointer to a struct but was %T", dst)
+	}
+
+	entries, err := pairs(r.content)
+	if err != nil {
+		return err
+	}
+
+	target := value.Elem()
+	info := structFields(target.Type())
+
+	for _, entry := range entries {
+		if entry.Value == nil {
+			continue
+		}
+
+		name, err := stringValue(entry.Key)
+		if err != nil {
+			return errors.Wrapf(err, "key %#v is not a string", entry.Key)
+		}
+
+		field, ok := info.byName[name]
+		if !ok {
+			continue
+		}
+
+		if err := scanField(target.FieldByIndex(field.index), entry.Value); err != nil {
+			return errors.Wrapf(err, "failed to scan field %v", name)
+		}
+	}
+
+	return nil
+}
+
+// StructArgs flattens a struct, or a pointer to one, into field and value pairs to be sent with HSET or
+// HMSET, like client.Do(ctx, append([]interface{}{"HSET", key}, args...)...). only fields tagged with
+// `redis:"name"` are included and fields tagged with `redis:"name,omitempty"` are skipped if they are
+// empty. values are formatted the same way Scan reads them, bools are sent as 1 and 0.
+func StructArgs(v interface{}) ([]interface{}, error) {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, errors.New("struct args can't be built from a nil pointer")
+		}
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("struct args need a struct or a pointer to a struct but got %T", v)
+	}
+
+	info := structFields(value.Type())
+
+	args := make([]interface{}, 0, len(info.fields)*2)
+	for _, field := range info.fields {
+		fieldValue := value.FieldByIndex(field.index)
+
+		if field.omitEmpty && fieldValue.IsZero() {
+			continue
+		}
+
+		arg, err := formatField(fieldValue)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to format field %v", field.name)
+		}
+
+		args = append(args, field.name, arg)
+	}
+
+	return args, nil
+}
+
+// structFields returns the tagged fields for a struct type.
+func structFields(t reflect.Type) *structInfo {
+	if info, ok := structFieldsCache.Load(t); ok {
+		return info.(*structInfo)
+	}
+
+	info := &structInfo{
+		byName: map[string]structField{},
+	}
+
+	for x := 0; x < t.NumField(); x++ {
+		field := t.Field(x)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup(structTag)
+		if !ok || tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if name == "" {
+			name = field.Name
+		}
+
+		omitEmpty := false
+		for _, option := range parts[1:] {
+			if option == "omitempty" {
+				omitEmpty = true
+			}
+		}
+
+		entry := structField{
+			name:      name,
+			index:     field.Index,
+			omitEmpty: omitEmpty,
+		}
+
+		info.fields = append(info.fields, entry)
+		info.byName[name] = entry
+	}
+
+	structFieldsCache.Store(t, info)
+
+	return info
+}
+
+// scanField sets the field with the value from the reply.
+func scanField(field reflect.Value, value interface{}) error {
+	text, err := textValue(value)
+	if err != nil {
+		return err
+	}
+
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(text)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported field type %v", field.Type())
+		}
+
+		field.SetBytes([]byte(text))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		result, err := strconv.ParseInt(text, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(result)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		result, err := strconv.ParseUint(text, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetUint(result)
+	case reflect.Float32, reflect.Float64:
+		result, err := strconv.ParseFloat(text, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetFloat(result)
+	case reflect.Bool:
+		result, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+
+		field.SetBool(result)
+	default:
+		return fmt.Errorf("unsupported field type %v", field.Type())
+	}
+
+	return nil
+}
+
+// formatField turns a field into the string sent to the server.
+func formatField(field reflect.Value) (interface{}, error) {
+	// pointers to TextMarshalers implement it too, but a nil one panics and Scan can't set them either
+	if field.Kind() == reflect.Ptr {
+		return nil, fmt.Errorf("unsupported field type %v", field.Type())
+	}
+
+	if field.Type().Implements(textMarshalerType) {
+		text, err := field.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+
+		return string(text), nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		return field.String(), nil
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.Uint8 {
+			return nil, fmt.Errorf("unsupported field type %v", field.Type())
+		}
+
+		return field.Bytes(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(field.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(field.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		value, err := formatFloat(field.Float(), field.Type().Bits())
+		if err != nil {
+			return nil, err
+		}
+
+		return string(value), nil
+	case reflect.Bool:
+		if field.Bool() {
+			return "1", nil
+		}
+
+		return "0", nil
+	}
+
+	return nil, fmt.Errorf("unsupported field type %v", field.Type())
+}
+
+// textValue returns the text for a reply value, RESP3 replies can have numbers and booleans as values.
+func textValue(value interface{}) (string, error) {
+	switch t := value.(type) {
+	case int64:
+		return strconv.FormatInt(t, 10), nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	}
+
+	return stringValue(value)
+}
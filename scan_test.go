@@ -0,0 +1,216 @@
+package redis_client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+type upperString string
+
+func (u *upperString) UnmarshalText(text []byte) error {
+	*u = upperString(strings.ToUpper(string(text)))
+	return nil
+}
+
+func (u upperString) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(string(u))), nil
+}
+
+type scanUser struct {
+	Name      string      `redis:"name"`
+	Avatar    []byte      `redis:"avatar"`
+	Age       int         `redis:"age"`
+	Visits    uint32      `redis:"visits"`
+	Score     float64     `redis:"score"`
+	Admin     bool        `redis:"admin"`
+	CreatedAt time.Time   `redis:"created_at"`
+	Country   upperString `redis:"country,omitempty"`
+	Ignored   string      `redis:"-"`
+	Untagged  string
+	private   string `redis:"private"`
+}
+
+func TestResult_Scan(t *testing.T) {
+	createdAt := time.Date(2021, 10, 5, 13, 30, 0, 0, time.UTC)
+
+	tt := []struct {
+		name   string
+		input  interface{}
+		result scanUser
+		err    string
+	}{
+		{
+			name: "a RESP2 array",
+			input: []interface{}{
+				"name", "joe",
+				"avatar", "png",
+				"age", "30",
+				"visits", "10",
+				"score", "9.5",
+				"admin", "1",
+				"created_at", "2021-10-05T13:30:00Z",
+				"country", "br",
+				"Ignored", "ignored",
+				"Untagged", "untagged",
+				"private", "private",
+				"unknown", "value",
+			},
+			result: scanUser{
+				Name:      "joe",
+				Avatar:    []byte("png"),
+				Age:       30,
+				Visits:    10,
+				Score:     9.5,
+				Admin:     true,
+				CreatedAt: createdAt,
+				Country:   "BR",
+			},
+		},
+		{
+			name: "a RESP3 map",
+			input: Map{
+				{Key: "name", Value: "joe"},
+				{Key: "age", Value: int64(30)},
+				{Key: "score", Value: 9.5},
+				{Key: "admin", Value: true},
+				{Key: "country", Value: nil},
+			},
+			result: scanUser{
+				Name:  "joe",
+				Age:   30,
+				Score: 9.5,
+				Admin: true,
+			},
+		},
+		{
+			name:  "an invalid integer",
+			input: []interface{}{"age", "thirty"},
+			err:   "failed to scan field age: strconv.ParseInt: parsing \"thirty\": invalid syntax",
+		},
+		{
+			name:  "an integer that overflows",
+			input: []interface{}{"visits", "5000000000"},
+			err:   "failed to scan field visits: strconv.ParseUint: parsing \"5000000000\": value out of range",
+		},
+		{
+			name:  "an odd array",
+			input: []interface{}{"name"},
+			err:   "array has an odd number of items (1) and can't be read as keys and values",
+		},
+		{
+			name:  "nil",
+			input: nil,
+			err:   "redis: nil",
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			user := scanUser{}
+			err := (&Result{content: ts.input}).Scan(&user)
+
+			if ts.err != "" {
+				assert.EqualError(t, err, ts.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, ts.result, user)
+		})
+	}
+}
+
+func TestResult_ScanInvalidDestination(t *testing.T) {
+	r := &Result{content: []interface{}{"name", "joe"}}
+
+	assert.EqualError(t, r.Scan(scanUser{}), "scan destination must be a non nil pointer to a struct but was redis_client.scanUser")
+	assert.EqualError(t, r.Scan((*scanUser)(nil)), "scan destination must be a non nil pointer to a struct but was *redis_client.scanUser")
+
+	value := ""
+	assert.EqualError(t, r.Scan(&value), "scan destination must be a non nil pointer to a struct but was *string")
+}
+
+func TestStructArgs(t *testing.T) {
+	args, err := StructArgs(&scanUser{
+		Name:      "joe",
+		Avatar:    []byte("png"),
+		Age:       30,
+		Visits:    10,
+		Score:     9.5,
+		Admin:     true,
+		CreatedAt: time.Date(2021, 10, 5, 13, 30, 0, 0, time.UTC),
+		Ignored:   "ignored",
+		Untagged:  "untagged",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []interface{}{
+		"name", "joe",
+		"avatar", []byte("png"),
+		"age", "30",
+		"visits", "10",
+		"score", "9.5",
+		"admin", "1",
+		"created_at", "2021-10-05T13:30:00Z",
+	}, args)
+
+	_, err = StructArgs("joe")
+	assert.EqualError(t, err, "struct args need a struct or a pointer to a struct but got string")
+
+	_, err = StructArgs(struct {
+		Tags []string `redis:"tags"`
+	}{})
+	assert.EqualError(t, err, "failed to format field tags: unsupported field type []string")
+
+	for _, deletedAt := range []*time.Time{nil, {}} {
+		_, err = StructArgs(struct {
+			DeletedAt *time.Time `redis:"deleted_at"`
+		}{DeletedAt: deletedAt})
+		assert.EqualError(t, err, "failed to format field deleted_at: unsupported field type *time.Time")
+	}
+
+	type scores struct {
+		Max float64 `redis:"max"`
+		Min float32 `redis:"min"`
+	}
+
+	args, err = StructArgs(scores{Max: math.Inf(1), Min: float32(math.Inf(-1))})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"max", "inf", "min", "-inf"}, args)
+
+	_, err = StructArgs(scores{Max: math.NaN()})
+	assert.EqualError(t, err, "failed to format field max: NaN can't be sent to redis")
+}
+
+func TestStructArgs_RoundTrip(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	user := scanUser{
+		Name:      "joe",
+		Avatar:    []byte("png"),
+		Age:       30,
+		Visits:    10,
+		Score:     9.5,
+		Admin:     true,
+		CreatedAt: time.Date(2021, 10, 5, 13, 30, 0, 0, time.UTC),
+		Country:   "BR",
+	}
+
+	args, err := StructArgs(user)
+	require.NoError(t, err)
+
+	_, err = client.Do(context.Background(), append([]interface{}{"HSET", "user:1"}, args...)...)
+	require.NoError(t, err)
+
+	result, err := client.Do(context.Background(), "HGETALL", "user:1")
+	require.NoError(t, err)
+
+	loaded := scanUser{}
+	require.NoError(t, result.Scan(&loaded))
+	assert.Equal(t, user, loaded)
+}
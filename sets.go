@@ -0,0 +1,98 @@
+package redis_client
+
+import (
+	"context"
+	"github.com/pkg/errors"
+)
+
+// SAdd adds the members to the set and returns how many of them were not there yet.
+func (c *Commands) SAdd(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	return c.int64(ctx, "SADD", key, members)
+}
+
+// SRem removes the members from the set and returns how many of them were there.
+func (c *Commands) SRem(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	return c.int64(ctx, "SREM", key, members)
+}
+
+// SMembers returns all members of the set.
+func (c *Commands) SMembers(ctx context.Context, key string) ([]string, error) {
+	return c.strings(ctx, "SMEMBERS", key)
+}
+
+// SIsMember returns true if member is on the set.
+func (c *Commands) SIsMember(ctx context.Context, key string, member interface{}) (bool, error) {
+	return c.bool(ctx, "SISMEMBER", key, member)
+}
+
+// SMIsMember returns if each of the members is on the set.
+func (c *Commands) SMIsMember(ctx context.Context, key string, members ...interface{}) ([]bool, error) {
+	result, err := c.result(ctx, "SMISMEMBER", key, members)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := result.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]bool, 0, len(items))
+	for x, item := range items {
+		value, err := item.Bool()
+		if err != nil {
+			return nil, errors.Wrapf(err, "element %v is not a bool", x)
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// SInter returns the members that are on all sets.
+func (c *Commands) SInter(ctx context.Context, keys ...string) ([]string, error) {
+	return c.strings(ctx, "SINTER", keys)
+}
+
+// SInterStore stores the members that are on all sets at destination and returns how many they are.
+func (c *Commands) SInterStore(ctx context.Context, destination string, keys ...string) (int64, error) {
+	return c.int64(ctx, "SINTERSTORE", destination, keys)
+}
+
+// SUnion returns the members that are on any of the sets.
+func (c *Commands) SUnion(ctx context.Context, keys ...string) ([]string, error) {
+	return c.strings(ctx, "SUNION", keys)
+}
+
+// SUnionStore stores the members that are on any of the sets at destination and returns how many they are.
+func (c *Commands) SUnionStore(ctx context.Context, destination string, keys ...string) (int64, error) {
+	return c.int64(ctx, "SUNIONSTORE", destination, keys)
+}
+
+// SDiff returns the members of the first set that are not on any of the other sets.
+func (c *Commands) SDiff(ctx context.Context, keys ...string) ([]string, error) {
+	return c.strings(ctx, "SDIFF", keys)
+}
+
+// SDiffStore stores the members of the first set that are not on any of the other sets at destination and
+// returns how many they are.
+func (c *Commands) SDiffStore(ctx context.Context, destination string, keys ...string) (int64, error) {
+	return c.int64(ctx, "SDIFFSTORE", destination, keys)
+}
+
+// SRandMember returns up to count random members, a negative count allows the same member to be returned
+// more than once.
+func (c *Commands) SRandMember(ctx context.Context, key string, count int64) ([]string, error) {
+	return c.strings(ctx, "SRANDMEMBER", key, count)
+}
+
+// SPop removes and returns a random member, it returns ErrNil if the set is empty.
+func (c *Commands) SPop(ctx context.Context, key string) (string, error) {
+	return c.string(ctx, "SPOP", key)
+}
+
+// SPopCount removes and returns up to count random members.
+func (c *Commands) SPopCount(ctx context.Context, key string, count int64) ([]string, error) {
+	return c.strings(ctx, "SPOP", key, count)
+}
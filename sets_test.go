@@ -0,0 +1,121 @@
+package redis_client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"sort"
+	"testing"
+)
+
+func TestCommands_Sets(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	added, err := client.SAdd(ctx, "a", "1", "2", 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), added)
+
+	added, err = client.SAdd(ctx, "b", "2", "3", "4")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), added)
+
+	isMember, err := client.SIsMember(ctx, "a", 1)
+	require.NoError(t, err)
+	assert.True(t, isMember)
+
+	isMember, err = client.SIsMember(ctx, "a", 4)
+	require.NoError(t, err)
+	assert.False(t, isMember)
+
+	tt := []struct {
+		name    string
+		members func() ([]string, error)
+		result  []string
+	}{
+		{
+			name:    "SMEMBERS",
+			members: func() ([]string, error) { return client.SMembers(ctx, "a") },
+			result:  []string{"1", "2", "3"},
+		},
+		{
+			name:    "SINTER",
+			members: func() ([]string, error) { return client.SInter(ctx, "a", "b") },
+			result:  []string{"2", "3"},
+		},
+		{
+			name:    "SUNION",
+			members: func() ([]string, error) { return client.SUnion(ctx, "a", "b") },
+			result:  []string{"1", "2", "3", "4"},
+		},
+		{
+			name:    "SDIFF",
+			members: func() ([]string, error) { return client.SDiff(ctx, "a", "b") },
+			result:  []string{"1"},
+		},
+		{
+			name:    "SMEMBERS on a missing key",
+			members: func() ([]string, error) { return client.SMembers(ctx, "missing") },
+			result:  []string{},
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			members, err := ts.members()
+			require.NoError(t, err)
+
+			sort.Strings(members)
+			assert.Equal(t, ts.result, members)
+		})
+	}
+
+	stored, err := client.SInterStore(ctx, "inter", "a", "b")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stored)
+
+	stored, err = client.SUnionStore(ctx, "union", "a", "b")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), stored)
+
+	stored, err = client.SDiffStore(ctx, "diff", "a", "b")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stored)
+
+	removed, err := client.SRem(ctx, "union", "1", "missing")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	random, err := client.SRandMember(ctx, "a", 2)
+	require.NoError(t, err)
+	assert.Len(t, random, 2)
+	assert.Subset(t, []string{"1", "2", "3"}, random)
+
+	popped, err := client.SPop(ctx, "diff")
+	require.NoError(t, err)
+	assert.Equal(t, "1", popped)
+
+	_, err = client.SPop(ctx, "diff")
+	assert.Equal(t, ErrNil, err)
+
+	poppedMembers, err := client.SPopCount(ctx, "inter", 5)
+	require.NoError(t, err)
+	sort.Strings(poppedMembers)
+	assert.Equal(t, []string{"2", "3"}, poppedMembers)
+}
+
+func TestCommands_SMIsMember(t *testing.T) {
+	server := newFakeServer(t, func(w io.Writer, args []string) {
+		io.WriteString(w, "*3\r\n:1\r\n:0\r\n:1\r\n")
+	})
+
+	client, err := Connect(context.Background(), server.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+
+	members, err := client.SMIsMember(context.Background(), "set", "a", "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false, true}, members)
+	assert.Equal(t, [][]string{{"SMISMEMBER", "set", "a", "b", "c"}}, server.Commands())
+}
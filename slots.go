@@ -0,0 +1,208 @@
+package redis_client
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	// clusterSlots is how many hash slots a cluster splits the keys into
+	clusterSlots = 16384
+)
+
+var (
+	// ErrCrossSlot is returned by a ClusterClient for commands with keys that map to different slots, these
+	// can't be executed by a single node. hashtags like {user:1}:name and {user:1}:email make keys map to
+	// the same slot.
+	ErrCrossSlot = errors.New("redis: keys don't map to the same slot")
+
+	// keylessCommands are routed to any node as they don't have keys
+	keylessCommands = map[string]bool{
+		"ACL":          true,
+		"ASKING":       true,
+		"AUTH":         true,
+		"BGREWRITEAOF": true,
+		"BGSAVE":       true,
+		"CLIENT":       true,
+		"CLUSTER":      true,
+		"COMMAND":      true,
+		"CONFIG":       true,
+		"DBSIZE":       true,
+		"DEBUG":        true,
+		"DISCARD":      true,
+		"ECHO":         true,
+		"EXEC":         true,
+		"FLUSHALL":     true,
+		"FLUSHDB":      true,
+		"FUNCTION":     true,
+		"HELLO":        true,
+		"INFO":         true,
+		"KEYS":         true,
+		"LASTSAVE":     true,
+		"LATENCY":      true,
+		"MODULE":       true,
+		"MONITOR":      true,
+		"MULTI":        true,
+		"PING":         true,
+		"PSUBSCRIBE":   true,
+		"PUBLISH":      true,
+		"PUBSUB":       true,
+		"PUNSUBSCRIBE": true,
+		"QUIT":         true,
+		"RANDOMKEY":    true,
+		"READONLY":     true,
+		"READWRITE":    true,
+		"RESET":        true,
+		"ROLE":         true,
+		"SAVE":         true,
+		"SCAN":         true,
+		"SCRIPT":       true,
+		"SELECT":       true,
+		"SLOWLOG":      true,
+		"SUBSCRIBE":    true,
+		"SWAPDB":       true,
+		"TIME":         true,
+		"UNSUBSCRIBE":  true,
+		"UNWATCH":      true,
+		"WAIT":         true,
+	}
+)
+
+// KeySlot returns the cluster hash slot for the key, the CRC16 of the key modulo 16384. if the key has a
+// hashtag, a non empty part between the first { and the next }, only the hashtag is hashed.
+func KeySlot(key string) int {
+	return keySlot([]byte(key))
+}
+
+func keySlot(key []byte) int {
+	if start := bytes.IndexByte(key, '{'); start >= 0 {
+		if end := bytes.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+
+	return int(crc16(key) % clusterSlots)
+}
+
+// crc16 is the CRC16-CCITT (XMODEM) checksum redis cluster uses for keys.
+func crc16(value []byte) uint16 {
+	var crc uint16
+	for _, b := range value {
+		crc ^= uint16(b) << 8
+		for x := 0; x < 8; x++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
+
+// commandSlot returns the slot all keys on the command map to, or -1 if the command has no keys.
+func commandSlot(args []interface{}) (int, error) {
+	flattened, err := flattenArgs(nil, args)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to execute operation: %v", args[0])
+	}
+
+	keys := commandKeys(flattened)
+	if len(keys) == 0 {
+		return -1, nil
+	}
+
+	slot := keySlot(keys[0])
+	for _, key := range keys[1:] {
+		if other := keySlot(key); other != slot {
+			return 0, errors.Wrapf(ErrCrossSlot, "%v has keys on slots %v and %v", string(flattened[0]), slot, other)
+		}
+	}
+
+	return slot, nil
+}
+
+// commandKeys returns the keys on a command, args are the command name and its flattened arguments.
+// commands that aren't known take the first argument as the key, which is where it is for most commands.
+func commandKeys(args [][]byte) [][]byte {
+	if len(args) < 2 {
+		return nil
+	}
+
+	command := strings.ToUpper(string(args[0]))
+	if keylessCommands[command] {
+		return nil
+	}
+
+	switch command {
+	case "MGET", "DEL", "UNLINK", "EXISTS", "TOUCH", "WATCH", "SINTER", "SUNION", "SDIFF", "SINTERSTORE",
+		"SUNIONSTORE", "SDIFFSTORE", "PFCOUNT", "PFMERGE", "SSUBSCRIBE", "SUNSUBSCRIBE":
+		return args[1:]
+	case "MSET", "MSETNX":
+		keys := make([][]byte, 0, len(args)/2)
+		for x := 1; x < len(args); x += 2 {
+			keys = append(keys, args[x])
+		}
+
+		return keys
+	case "RENAME", "RENAMENX", "COPY", "SMOVE", "LMOVE", "BLMOVE", "RPOPLPUSH", "BRPOPLPUSH", "ZRANGESTORE",
+		"GEOSEARCHSTORE":
+		return limitArgs(args, 1, 3)
+	case "BLPOP", "BRPOP", "BZPOPMIN", "BZPOPMAX":
+		// the timeout is the last argument
+		return args[1 : len(args)-1]
+	case "ZUNIONSTORE", "ZINTERSTORE", "ZDIFFSTORE":
+		// destination numkeys key [key ...]
+		return append([][]byte{args[1]}, numKeys(args, 2)...)
+	case "ZUNION", "ZINTER", "ZDIFF", "ZINTERCARD", "SINTERCARD", "LMPOP", "ZMPOP":
+		return numKeys(args, 1)
+	case "BLMPOP", "BZMPOP", "EVAL", "EVALSHA", "EVAL_RO", "EVALSHA_RO", "FCALL", "FCALL_RO":
+		// the timeout, script or function comes before numkeys
+		return numKeys(args, 2)
+	case "XREAD", "XREADGROUP":
+		// STREAMS key [key ...] id [id ...]
+		for x := 1; x < len(args); x++ {
+			if strings.EqualFold(string(args[x]), "STREAMS") {
+				streams := args[x+1:]
+				return streams[:len(streams)/2]
+			}
+		}
+
+		return nil
+	case "OBJECT", "MEMORY", "XINFO":
+		// the key comes after the subcommand
+		return limitArgs(args, 2, 3)
+	}
+
+	return args[1:2]
+}
+
+// numKeys returns the keys after a numkeys argument at index.
+func numKeys(args [][]byte, index int) [][]byte {
+	if index >= len(args) {
+		return nil
+	}
+
+	count, err := strconv.Atoi(string(args[index]))
+	if err != nil || count < 0 {
+		return nil
+	}
+
+	return limitArgs(args, index+1, index+1+count)
+}
+
+// limitArgs returns args[start:end] with both limited to the args that are there.
+func limitArgs(args [][]byte, start int, end int) [][]byte {
+	if end > len(args) {
+		end = len(args)
+	}
+
+	if start > end {
+		return nil
+	}
+
+	return args[start:end]
+}
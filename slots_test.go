@@ -0,0 +1,88 @@
+package redis_client
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestKeySlot(t *testing.T) {
+	tt := []struct {
+		key  string
+		slot int
+	}{
+		{key: "123456789", slot: 12739},
+		{key: "foo", slot: 12182},
+		{key: "a", slot: 15495},
+		{key: "", slot: 0},
+		{key: "{user1000}.following", slot: KeySlot("user1000")},
+		{key: "{user1000}.followers", slot: KeySlot("user1000")},
+		// empty hashtags are ignored and the whole key is hashed
+		{key: "foo{}{bar}", slot: int(crc16([]byte("foo{}{bar}")) % clusterSlots)},
+		{key: "foo{{bar}}zap", slot: KeySlot("{bar")},
+		{key: "foo{bar}{zap}", slot: KeySlot("bar")},
+		{key: "foo{bar", slot: int(crc16([]byte("foo{bar")) % clusterSlots)},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.key, func(t *testing.T) {
+			assert.Equal(t, ts.slot, KeySlot(ts.key))
+		})
+	}
+}
+
+func TestCommandKeys(t *testing.T) {
+	tt := []struct {
+		name string
+		args []interface{}
+		keys []string
+	}{
+		{name: "single key", args: []interface{}{"GET", "a"}, keys: []string{"a"}},
+		{name: "lowercase command", args: []interface{}{"hset", "a", "field", "value"}, keys: []string{"a"}},
+		{name: "keyless", args: []interface{}{"PING", "hello"}},
+		{name: "no arguments", args: []interface{}{"DBSIZE"}},
+		{name: "all keys", args: []interface{}{"DEL", []string{"a", "b", "c"}}, keys: []string{"a", "b", "c"}},
+		{name: "keys and values", args: []interface{}{"MSET", "a", 1, "b", 2}, keys: []string{"a", "b"}},
+		{name: "source and destination", args: []interface{}{"LMOVE", "a", "b", "LEFT", "RIGHT"}, keys: []string{"a", "b"}},
+		{name: "timeout at the end", args: []interface{}{"BLPOP", "a", "b", 0}, keys: []string{"a", "b"}},
+		{name: "destination and numkeys", args: []interface{}{"ZUNIONSTORE", "d", 2, "a", "b", "WEIGHTS", 1, 2}, keys: []string{"d", "a", "b"}},
+		{name: "numkeys first", args: []interface{}{"ZUNION", 2, "a", "b", "WITHSCORES"}, keys: []string{"a", "b"}},
+		{name: "scripts", args: []interface{}{"EVAL", "return 1", 1, "a", "argument"}, keys: []string{"a"}},
+		{name: "numkeys out of range", args: []interface{}{"EVALSHA", "sha", 5, "a"}, keys: []string{"a"}},
+		{name: "streams", args: []interface{}{"XREAD", "COUNT", 2, "STREAMS", "a", "b", "0", "0"}, keys: []string{"a", "b"}},
+		{name: "subcommand", args: []interface{}{"OBJECT", "ENCODING", "a"}, keys: []string{"a"}},
+		{name: "sharded channel", args: []interface{}{"SPUBLISH", "channel", "message"}, keys: []string{"channel"}},
+		{name: "sharded channels", args: []interface{}{"SSUBSCRIBE", "a", "b"}, keys: []string{"a", "b"}},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			flattened, err := flattenArgs(nil, ts.args)
+			require.NoError(t, err)
+
+			var keys []string
+			for _, key := range commandKeys(flattened) {
+				keys = append(keys, string(key))
+			}
+
+			assert.Equal(t, ts.keys, keys)
+		})
+	}
+}
+
+func TestCommandSlot(t *testing.T) {
+	slot, err := commandSlot([]interface{}{"MGET", "{user:1}:name", "{user:1}:email"})
+	require.NoError(t, err)
+	assert.Equal(t, KeySlot("user:1"), slot)
+
+	slot, err = commandSlot([]interface{}{"PING"})
+	require.NoError(t, err)
+	assert.Equal(t, -1, slot)
+
+	_, err = commandSlot([]interface{}{"MSET", map[string]string{"a": "1", "b": "2"}})
+	assert.True(t, errors.Is(err, ErrCrossSlot))
+
+	_, err = commandSlot([]interface{}{"GET", struct{}{}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,318 @@
+package redis_client
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// Z is a sorted set member with its score.
+type Z struct {
+	Member string
+	Score  float64
+}
+
+// NullFloat64 is a float reply that can be null, like the scores for missing members on ZMSCORE.
+type NullFloat64 struct {
+	Float64 float64
+	Valid   bool
+}
+
+// ZAddOptions are the options for ZADD, the zero value adds new members and updates the scores of the
+// ones that are already there.
+type ZAddOptions struct {
+	// NX only adds new members, scores of existing members are not updated.
+	NX bool
+	// XX only updates existing members, no members are added.
+	XX bool
+	// GT only updates scores if the new score is greater than the current one.
+	GT bool
+	// LT only updates scores if the new score is less than the current one.
+	LT bool
+	// CH makes ZAdd return the number of members added or changed instead of only the added ones.
+	CH bool
+}
+
+func (o ZAddOptions) args(key string) []interface{} {
+	args := []interface{}{"ZADD", key}
+
+	for _, option := range []struct {
+		enabled bool
+		name    string
+	}{
+		{o.NX, "NX"},
+		{o.XX, "XX"},
+		{o.GT, "GT"},
+		{o.LT, "LT"},
+		{o.CH, "CH"},
+	} {
+		if option.enabled {
+			args = append(args, option.name)
+		}
+	}
+
+	return args
+}
+
+// ZRangeBy is how ZRANGE reads start and stop, by rank (the index on the sorted set), by score or by member.
+type ZRangeBy string
+
+const (
+	ZRangeByRank  ZRangeBy = ""
+	ZRangeByScore ZRangeBy = "BYSCORE"
+	ZRangeByLex   ZRangeBy = "BYLEX"
+)
+
+// ZRangeOptions are the options for ZRANGE, the zero value is a range by rank from the lowest score.
+type ZRangeOptions struct {
+	By ZRangeBy
+	// Rev returns members from the highest score to the lowest, start and stop must be reversed as well
+	// for ranges by score or member.
+	Rev bool
+	// Offset and Count limit the results, only for ranges by score or member. Count is only sent if it isn't 0,
+	// a negative Count returns all members after Offset.
+	Offset int64
+	Count  int64
+}
+
+func (o ZRangeOptions) args(key string, start interface{}, stop interface{}) []interface{} {
+	args := []interface{}{"ZRANGE", key, start, stop}
+
+	if o.By != ZRangeByRank {
+		args = append(args, string(o.By))
+	}
+
+	if o.Rev {
+		args = append(args, "REV")
+	}
+
+	if o.Count != 0 {
+		args = append(args, "LIMIT", o.Offset, o.Count)
+	}
+
+	return args
+}
+
+// ZAggregate is how ZUNIONSTORE and ZINTERSTORE combine the scores of a member that is on many sets.
+type ZAggregate string
+
+const (
+	ZAggregateSum ZAggregate = "SUM"
+	ZAggregateMin ZAggregate = "MIN"
+	ZAggregateMax ZAggregate = "MAX"
+)
+
+// ZStoreOptions are the options for ZUNIONSTORE and ZINTERSTORE, the zero value sums the scores with no weights.
+type ZStoreOptions struct {
+	// Weights multiply the scores of each set, if set there must be one weight per key.
+	Weights   []float64
+	Aggregate ZAggregate
+}
+
+func (o ZStoreOptions) args(command string, destination string, keys []string) []interface{} {
+	args := []interface{}{command, destination, len(keys), keys}
+
+	if len(o.Weights) > 0 {
+		args = append(args, "WEIGHTS", o.Weights)
+	}
+
+	if o.Aggregate != "" {
+		args = append(args, "AGGREGATE", string(o.Aggregate))
+	}
+
+	return args
+}
+
+// ZAdd adds the members to the sorted set and returns how many were added, or how many were added or
+// changed with the CH option.
+func (c *Commands) ZAdd(ctx context.Context, key string, options ZAddOptions, members ...Z) (int64, error) {
+	args := options.args(key)
+	for _, member := range members {
+		args = append(args, member.Score, member.Member)
+	}
+
+	return c.int64(ctx, args...)
+}
+
+// ZAddIncr increments the score of the member like ZIncrBy but respecting the options, it returns the new
+// score or ErrNil if the options prevented the member from being changed.
+func (c *Commands) ZAddIncr(ctx context.Context, key string, options ZAddOptions, member Z) (float64, error) {
+	return c.float64(ctx, append(options.args(key), "INCR", member.Score, member.Member)...)
+}
+
+// ZRange returns the members between start and stop. for ranges by rank these are indexes, negative ones
+// start from the end. for ranges by score these are scores, like 1.5, "(1.5" for an exclusive score or
+// "-inf" and "+inf". for ranges by member these are members starting with [ or ( for inclusive or exclusive
+// ranges or - and + for the ends of the set.
+func (c *Commands) ZRange(ctx context.Context, key string, start interface{}, stop interface{}, options ZRangeOptions) ([]string, error) {
+	return c.strings(ctx, options.args(key, start, stop)...)
+}
+
+// ZRangeWithScores returns the members between start and stop like ZRange, with their scores.
+func (c *Commands) ZRangeWithScores(ctx context.Context, key string, start interface{}, stop interface{}, options ZRangeOptions) ([]Z, error) {
+	return c.zs(ctx, append(options.args(key, start, stop), "WITHSCORES")...)
+}
+
+// ZRank returns the rank of the member from the lowest score, it returns ErrNil if the member isn't on the set.
+func (c *Commands) ZRank(ctx context.Context, key string, member interface{}) (int64, error) {
+	return c.int64(ctx, "ZRANK", key, member)
+}
+
+// ZRevRank returns the rank of the member from the highest score, it returns ErrNil if the member isn't on
+// the set.
+func (c *Commands) ZRevRank(ctx context.Context, key string, member interface{}) (int64, error) {
+	return c.int64(ctx, "ZREVRANK", key, member)
+}
+
+// ZScore returns the score of the member, it returns ErrNil if the member isn't on the set.
+func (c *Commands) ZScore(ctx context.Context, key string, member interface{}) (float64, error) {
+	return c.float64(ctx, "ZSCORE", key, member)
+}
+
+// ZMScore returns the scores of the members, members that aren't on the set are not Valid.
+func (c *Commands) ZMScore(ctx context.Context, key string, members ...interface{}) ([]NullFloat64, error) {
+	result, err := c.result(ctx, "ZMSCORE", key, members)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := result.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]NullFloat64, 0, len(items))
+	for x, item := range items {
+		if item.content == nil {
+			scores = append(scores, NullFloat64{})
+			continue
+		}
+
+		score, err := item.Float64()
+		if err != nil {
+			return nil, errors.Wrapf(err, "element %v is not a score", x)
+		}
+
+		scores = append(scores, NullFloat64{Float64: score, Valid: true})
+	}
+
+	return scores, nil
+}
+
+// ZIncrBy increments the score of the member and returns the new score.
+func (c *Commands) ZIncrBy(ctx context.Context, key string, increment float64, member interface{}) (float64, error) {
+	return c.float64(ctx, "ZINCRBY", key, increment, member)
+}
+
+// ZPopMin removes and returns up to count members with the lowest scores.
+func (c *Commands) ZPopMin(ctx context.Context, key string, count int64) ([]Z, error) {
+	return c.zs(ctx, "ZPOPMIN", key, count)
+}
+
+// ZPopMax removes and returns up to count members with the highest scores.
+func (c *Commands) ZPopMax(ctx context.Context, key string, count int64) ([]Z, error) {
+	return c.zs(ctx, "ZPOPMAX", key, count)
+}
+
+// BZPopMin removes and returns the member with the lowest score from the first of the keys that isn't empty,
+// waiting up to timeout for a member to be added if all of them are empty. it returns the key the member
+// was taken from or ErrNil if the timeout expires. a 0 timeout waits forever.
+func (c *Commands) BZPopMin(ctx context.Context, timeout time.Duration, keys ...string) (string, Z, error) {
+	result, err := c.result(ctx, "BZPOPMIN", keys, timeout.Seconds())
+	if err != nil {
+		return "", Z{}, err
+	}
+
+	if result.content == nil {
+		return "", Z{}, ErrNil
+	}
+
+	if result.Len() != 3 {
+		return "", Z{}, fmt.Errorf("BZPOPMIN reply should have 3 elements: %#v", result.content)
+	}
+
+	key, err := stringValue(result.Index(0).content)
+	if err != nil {
+		return "", Z{}, errors.Wrap(err, "invalid key")
+	}
+
+	member, err := stringValue(result.Index(1).content)
+	if err != nil {
+		return "", Z{}, errors.Wrap(err, "invalid member")
+	}
+
+	score, err := result.Index(2).Float64()
+	if err != nil {
+		return "", Z{}, errors.Wrap(err, "invalid score")
+	}
+
+	return key, Z{Member: member, Score: score}, nil
+}
+
+// ZUnionStore stores the union of the sorted sets at destination and returns how many members it has.
+func (c *Commands) ZUnionStore(ctx context.Context, destination string, options ZStoreOptions, keys ...string) (int64, error) {
+	return c.int64(ctx, options.args("ZUNIONSTORE", destination, keys)...)
+}
+
+// ZInterStore stores the intersection of the sorted sets at destination and returns how many members it has.
+func (c *Commands) ZInterStore(ctx context.Context, destination string, options ZStoreOptions, keys ...string) (int64, error) {
+	return c.int64(ctx, options.args("ZINTERSTORE", destination, keys)...)
+}
+
+// ZRemRangeByScore removes the members with scores between min and max and returns how many were removed.
+// min and max are scores, like 1.5, "(1.5" for an exclusive score or "-inf" and "+inf".
+func (c *Commands) ZRemRangeByScore(ctx context.Context, key string, min interface{}, max interface{}) (int64, error) {
+	return c.int64(ctx, "ZREMRANGEBYSCORE", key, min, max)
+}
+
+// zs reads members with scores, RESP2 sends them as a flat array of members and scores and RESP3 as an
+// array of member and score pairs.
+func (c *Commands) zs(ctx context.Context, args ...interface{}) ([]Z, error) {
+	result, err := c.result(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := result.Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) > 0 {
+		if _, nested := values[0].([]interface{}); nested {
+			flattened := make([]interface{}, 0, len(values)*2)
+			for _, value := range values {
+				pair, ok := value.([]interface{})
+				if !ok || len(pair) != 2 {
+					return nil, fmt.Errorf("member and score pair should be an array of 2 elements: %#v", value)
+				}
+
+				flattened = append(flattened, pair...)
+			}
+
+			values = flattened
+		}
+	}
+
+	if len(values)%2 != 0 {
+		return nil, fmt.Errorf("array has an odd number of items (%v) and can't be read as members and scores", len(values))
+	}
+
+	zs := make([]Z, 0, len(values)/2)
+	for x := 0; x < len(values); x += 2 {
+		member, err := stringValue(values[x])
+		if err != nil {
+			return nil, errors.Wrapf(err, "member %v is invalid", x/2)
+		}
+
+		score, err := (&Result{content: values[x+1]}).Float64()
+		if err != nil {
+			return nil, errors.Wrapf(err, "score for member %v is invalid", member)
+		}
+
+		zs = append(zs, Z{Member: member, Score: score})
+	}
+
+	return zs, nil
+}
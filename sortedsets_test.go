@@ -0,0 +1,289 @@
+package redis_client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCommands_ZAdd(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	added, err := client.ZAdd(ctx, "board", ZAddOptions{}, Z{Member: "joe", Score: 10}, Z{Member: "mary", Score: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), added)
+
+	added, err = client.ZAdd(ctx, "board", ZAddOptions{NX: true}, Z{Member: "joe", Score: 100}, Z{Member: "bob", Score: 5})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), added)
+
+	changed, err := client.ZAdd(ctx, "board", ZAddOptions{XX: true, CH: true}, Z{Member: "joe", Score: 15}, Z{Member: "ann", Score: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), changed)
+
+	score, err := client.ZAddIncr(ctx, "board", ZAddOptions{}, Z{Member: "bob", Score: 2.5})
+	require.NoError(t, err)
+	assert.Equal(t, 7.5, score)
+
+	_, err = client.ZAddIncr(ctx, "board", ZAddOptions{XX: true}, Z{Member: "ann", Score: 1})
+	assert.Equal(t, ErrNil, err)
+
+	members, err := client.ZRangeWithScores(ctx, "board", 0, -1, ZRangeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []Z{{Member: "bob", Score: 7.5}, {Member: "joe", Score: 15}, {Member: "mary", Score: 20}}, members)
+}
+
+func TestCommands_ZRange(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.ZAdd(ctx, "board", ZAddOptions{},
+		Z{Member: "a", Score: 1},
+		Z{Member: "b", Score: 2},
+		Z{Member: "c", Score: 3},
+		Z{Member: "d", Score: math.Inf(1)},
+	)
+	require.NoError(t, err)
+
+	members, err := client.ZRange(ctx, "board", 0, 1, ZRangeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, members)
+
+	withScores, err := client.ZRangeWithScores(ctx, "board", 2, -1, ZRangeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []Z{{Member: "c", Score: 3}, {Member: "d", Score: math.Inf(1)}}, withScores)
+}
+
+func TestZAddOptions_args(t *testing.T) {
+	tt := []struct {
+		name    string
+		options ZAddOptions
+		args    []interface{}
+	}{
+		{
+			name: "no options",
+			args: []interface{}{"ZADD", "board"},
+		},
+		{
+			name:    "all options",
+			options: ZAddOptions{NX: true, XX: true, GT: true, LT: true, CH: true},
+			args:    []interface{}{"ZADD", "board", "NX", "XX", "GT", "LT", "CH"},
+		},
+		{
+			name:    "greater than with changes",
+			options: ZAddOptions{GT: true, CH: true},
+			args:    []interface{}{"ZADD", "board", "GT", "CH"},
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			assert.Equal(t, ts.args, ts.options.args("board"))
+		})
+	}
+}
+
+func TestZRangeOptions_args(t *testing.T) {
+	tt := []struct {
+		name        string
+		start, stop interface{}
+		options     ZRangeOptions
+		args        []interface{}
+	}{
+		{
+			name:  "by rank",
+			start: 0,
+			stop:  -1,
+			args:  []interface{}{"ZRANGE", "board", 0, -1},
+		},
+		{
+			name:    "by rank reversed",
+			start:   0,
+			stop:    1,
+			options: ZRangeOptions{Rev: true},
+			args:    []interface{}{"ZRANGE", "board", 0, 1, "REV"},
+		},
+		{
+			name:    "by score with a limit",
+			start:   "(1",
+			stop:    "+inf",
+			options: ZRangeOptions{By: ZRangeByScore, Offset: 1, Count: 2},
+			args:    []interface{}{"ZRANGE", "board", "(1", "+inf", "BYSCORE", "LIMIT", int64(1), int64(2)},
+		},
+		{
+			name:    "by member reversed",
+			start:   "[c",
+			stop:    "-",
+			options: ZRangeOptions{By: ZRangeByLex, Rev: true, Offset: 2, Count: -1},
+			args:    []interface{}{"ZRANGE", "board", "[c", "-", "BYLEX", "REV", "LIMIT", int64(2), int64(-1)},
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			assert.Equal(t, ts.args, ts.options.args("board", ts.start, ts.stop))
+		})
+	}
+}
+
+func TestZStoreOptions_args(t *testing.T) {
+	keys := []string{"a", "b"}
+
+	tt := []struct {
+		name    string
+		options ZStoreOptions
+		args    []interface{}
+	}{
+		{
+			name: "no options",
+			args: []interface{}{"ZUNIONSTORE", "result", 2, keys},
+		},
+		{
+			name:    "weights and aggregate",
+			options: ZStoreOptions{Weights: []float64{2, 0.5}, Aggregate: ZAggregateMin},
+			args:    []interface{}{"ZUNIONSTORE", "result", 2, keys, "WEIGHTS", []float64{2, 0.5}, "AGGREGATE", "MIN"},
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			assert.Equal(t, ts.args, ts.options.args("ZUNIONSTORE", "result", keys))
+		})
+	}
+}
+
+func TestCommands_ZScores(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.ZAdd(ctx, "board", ZAddOptions{}, Z{Member: "a", Score: 1}, Z{Member: "b", Score: 2}, Z{Member: "c", Score: 3})
+	require.NoError(t, err)
+
+	rank, err := client.ZRank(ctx, "board", "b")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), rank)
+
+	rank, err = client.ZRevRank(ctx, "board", "a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), rank)
+
+	_, err = client.ZRank(ctx, "board", "missing")
+	assert.Equal(t, ErrNil, err)
+
+	score, err := client.ZScore(ctx, "board", "c")
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, score)
+
+	_, err = client.ZScore(ctx, "board", "missing")
+	assert.Equal(t, ErrNil, err)
+
+	score, err = client.ZIncrBy(ctx, "board", 1.5, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 2.5, score)
+
+	removed, err := client.ZRemRangeByScore(ctx, "board", "-inf", "(3")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), removed)
+
+	members, err := client.ZRange(ctx, "board", 0, -1, ZRangeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, members)
+}
+
+func TestCommands_ZPop(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.ZAdd(ctx, "board", ZAddOptions{}, Z{Member: "a", Score: 1}, Z{Member: "b", Score: 2}, Z{Member: "c", Score: 3})
+	require.NoError(t, err)
+
+	members, err := client.ZPopMin(ctx, "board", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []Z{{Member: "a", Score: 1}, {Member: "b", Score: 2}}, members)
+
+	members, err = client.ZPopMax(ctx, "board", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []Z{{Member: "c", Score: 3}}, members)
+
+	members, err = client.ZPopMax(ctx, "board", 2)
+	require.NoError(t, err)
+	assert.Empty(t, members)
+}
+
+func TestCommands_ZStore(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.ZAdd(ctx, "a", ZAddOptions{}, Z{Member: "x", Score: 1}, Z{Member: "y", Score: 2})
+	require.NoError(t, err)
+
+	_, err = client.ZAdd(ctx, "b", ZAddOptions{}, Z{Member: "y", Score: 10}, Z{Member: "z", Score: 20})
+	require.NoError(t, err)
+
+	count, err := client.ZUnionStore(ctx, "union", ZStoreOptions{Weights: []float64{2, 1}}, "a", "b")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	members, err := client.ZRangeWithScores(ctx, "union", 0, -1, ZRangeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []Z{{Member: "x", Score: 2}, {Member: "y", Score: 14}, {Member: "z", Score: 20}}, members)
+
+	count, err = client.ZInterStore(ctx, "inter", ZStoreOptions{Aggregate: ZAggregateMax}, "a", "b")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	members, err = client.ZRangeWithScores(ctx, "inter", 0, -1, ZRangeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []Z{{Member: "y", Score: 10}}, members)
+}
+
+func TestCommands_SortedSetRESP3(t *testing.T) {
+	server := newFakeServer(t, func(w io.Writer, args []string) {
+		switch args[0] {
+		case "ZMSCORE":
+			io.WriteString(w, "*3\r\n,1.5\r\n_\r\n$3\r\ninf\r\n")
+		case "ZRANGE":
+			io.WriteString(w, "*2\r\n*2\r\n$1\r\na\r\n,1\r\n*2\r\n$1\r\nb\r\n,-inf\r\n")
+		case "BZPOPMIN":
+			if args[1] == "empty" {
+				io.WriteString(w, "_\r\n")
+			} else {
+				io.WriteString(w, "*3\r\n$5\r\nboard\r\n$1\r\na\r\n,1.5\r\n")
+			}
+		}
+	})
+
+	client, err := Connect(context.Background(), server.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx := context.Background()
+
+	scores, err := client.ZMScore(ctx, "board", "a", "missing", "c")
+	require.NoError(t, err)
+	assert.Equal(t, []NullFloat64{{Float64: 1.5, Valid: true}, {}, {Float64: math.Inf(1), Valid: true}}, scores)
+
+	members, err := client.ZRangeWithScores(ctx, "board", 0, -1, ZRangeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []Z{{Member: "a", Score: 1}, {Member: "b", Score: math.Inf(-1)}}, members)
+
+	key, member, err := client.BZPopMin(ctx, time.Millisecond*500, "board", "other")
+	require.NoError(t, err)
+	assert.Equal(t, "board", key)
+	assert.Equal(t, Z{Member: "a", Score: 1.5}, member)
+
+	_, _, err = client.BZPopMin(ctx, time.Second, "empty")
+	assert.Equal(t, ErrNil, err)
+
+	assert.Equal(t, [][]string{
+		{"ZMSCORE", "board", "a", "missing", "c"},
+		{"ZRANGE", "board", "0", "-1", "WITHSCORES"},
+		{"BZPOPMIN", "board", "other", "0.5"},
+		{"BZPOPMIN", "empty", "1"},
+	}, server.Commands())
+}
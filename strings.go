@@ -0,0 +1,120 @@
+package redis_client
+
+import (
+	"context"
+	"time"
+)
+
+// SetOptions are the options for SET, the zero value sets the key with no expiration.
+type SetOptions struct {
+	// Expiration is sent as EX if it is a whole number of seconds and as PX if it isn't, expirations
+	// under a millisecond fail.
+	Expiration time.Duration
+	// NX only sets the key if it doesn't exist.
+	NX bool
+	// XX only sets the key if it already exists.
+	XX bool
+	// KeepTTL keeps the expiration the key already has.
+	KeepTTL bool
+}
+
+func (o SetOptions) args(key string, value interface{}) ([]interface{}, error) {
+	if err := checkExpiration(o.Expiration); err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{"SET", key, value}
+
+	switch {
+	case o.Expiration <= 0:
+	case o.Expiration%time.Second == 0:
+		args = append(args, "EX", int64(o.Expiration/time.Second))
+	default:
+		args = append(args, "PX", o.Expiration.Milliseconds())
+	}
+
+	if o.NX {
+		args = append(args, "NX")
+	}
+
+	if o.XX {
+		args = append(args, "XX")
+	}
+
+	if o.KeepTTL {
+		args = append(args, "KEEPTTL")
+	}
+
+	return args, nil
+}
+
+// Get returns the value at key or ErrNil if the key doesn't exist.
+func (c *Commands) Get(ctx context.Context, key string) (string, error) {
+	return c.string(ctx, "GET", key)
+}
+
+// Set sets key to value, it returns false if the value was not set because of the NX or XX options.
+func (c *Commands) Set(ctx context.Context, key string, value interface{}, options SetOptions) (bool, error) {
+	args, err := options.args(key, value)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := c.result(ctx, args...)
+	if err != nil {
+		return false, err
+	}
+
+	return result.content != nil, nil
+}
+
+// SetGet sets key to value with the GET option and returns the value that was there before, or ErrNil if
+// the key didn't exist.
+func (c *Commands) SetGet(ctx context.Context, key string, value interface{}, options SetOptions) (string, error) {
+	args, err := options.args(key, value)
+	if err != nil {
+		return "", err
+	}
+
+	return c.string(ctx, append(args, "GET")...)
+}
+
+// MGet returns the values for all keys, missing keys are not Valid.
+func (c *Commands) MGet(ctx context.Context, keys ...string) ([]NullString, error) {
+	return c.nullStrings(ctx, "MGET", keys)
+}
+
+// MSet sets many keys at once, keysAndValues are key and value pairs or maps of keys to values.
+func (c *Commands) MSet(ctx context.Context, keysAndValues ...interface{}) error {
+	return c.status(ctx, "MSET", keysAndValues)
+}
+
+// Incr increments the integer at key by one and returns the new value.
+func (c *Commands) Incr(ctx context.Context, key string) (int64, error) {
+	return c.int64(ctx, "INCR", key)
+}
+
+// IncrBy increments the integer at key by increment and returns the new value.
+func (c *Commands) IncrBy(ctx context.Context, key string, increment int64) (int64, error) {
+	return c.int64(ctx, "INCRBY", key, increment)
+}
+
+// IncrByFloat increments the number at key by increment and returns the new value.
+func (c *Commands) IncrByFloat(ctx context.Context, key string, increment float64) (float64, error) {
+	return c.float64(ctx, "INCRBYFLOAT", key, increment)
+}
+
+// Append appends value to the string at key and returns the new length of the string.
+func (c *Commands) Append(ctx context.Context, key string, value interface{}) (int64, error) {
+	return c.int64(ctx, "APPEND", key, value)
+}
+
+// GetRange returns the substring from start to end, both inclusive, negative offsets start from the end.
+func (c *Commands) GetRange(ctx context.Context, key string, start int64, end int64) (string, error) {
+	return c.string(ctx, "GETRANGE", key, start, end)
+}
+
+// SetRange overwrites the string at key starting at offset and returns the new length of the string.
+func (c *Commands) SetRange(ctx context.Context, key string, offset int64, value interface{}) (int64, error) {
+	return c.int64(ctx, "SETRANGE", key, offset, value)
+}
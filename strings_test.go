@@ -0,0 +1,200 @@
+package redis_client
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestCommands_GetSet(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.Get(ctx, "some-key")
+	assert.Equal(t, ErrNil, err)
+
+	set, err := client.Set(ctx, "some-key", "some-value", SetOptions{})
+	require.NoError(t, err)
+	assert.True(t, set)
+
+	value, err := client.Get(ctx, "some-key")
+	require.NoError(t, err)
+	assert.Equal(t, "some-value", value)
+
+	set, err = client.Set(ctx, "some-key", "other-value", SetOptions{NX: true})
+	require.NoError(t, err)
+	assert.False(t, set)
+
+	set, err = client.Set(ctx, "missing-key", "other-value", SetOptions{XX: true})
+	require.NoError(t, err)
+	assert.False(t, set)
+
+	set, err = client.Set(ctx, "expiring-key", 10, SetOptions{Expiration: time.Minute})
+	require.NoError(t, err)
+	assert.True(t, set)
+	assert.Equal(t, time.Minute, server.TTL("expiring-key"))
+
+	set, err = client.Set(ctx, "expiring-key", 10, SetOptions{Expiration: time.Millisecond * 1500})
+	require.NoError(t, err)
+	assert.True(t, set)
+	assert.Equal(t, time.Millisecond*1500, server.TTL("expiring-key"))
+
+	previous, err := client.SetGet(ctx, "some-key", "new-value", SetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "some-value", previous)
+
+	_, err = client.SetGet(ctx, "new-key", "new-value", SetOptions{})
+	assert.Equal(t, ErrNil, err)
+}
+
+func TestSetOptions_Args(t *testing.T) {
+	tt := []struct {
+		name    string
+		options SetOptions
+		args    []interface{}
+		err     string
+	}{
+		{
+			name: "no options",
+			args: []interface{}{"SET", "key", "value"},
+		},
+		{
+			name:    "seconds",
+			options: SetOptions{Expiration: time.Second * 10, NX: true},
+			args:    []interface{}{"SET", "key", "value", "EX", int64(10), "NX"},
+		},
+		{
+			name:    "milliseconds",
+			options: SetOptions{Expiration: time.Millisecond * 10, XX: true},
+			args:    []interface{}{"SET", "key", "value", "PX", int64(10), "XX"},
+		},
+		{
+			name:    "keep ttl",
+			options: SetOptions{KeepTTL: true},
+			args:    []interface{}{"SET", "key", "value", "KEEPTTL"},
+		},
+		{
+			name:    "under a millisecond",
+			options: SetOptions{Expiration: time.Microsecond * 500},
+			err:     "expiration of 500µs is under a millisecond",
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			args, err := ts.options.args("key", "value")
+			if ts.err != "" {
+				assert.EqualError(t, err, ts.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, ts.args, args)
+		})
+	}
+}
+
+func TestCommands_MGetMSet(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	require.NoError(t, client.MSet(ctx, "a", "1", map[string]interface{}{"b": 2, "c": "3"}))
+
+	values, err := client.MGet(ctx, "a", "missing", "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, []NullString{
+		{String: "1", Valid: true},
+		{},
+		{String: "2", Valid: true},
+		{String: "3", Valid: true},
+	}, values)
+}
+
+func TestCommands_Numbers(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	value, err := client.Incr(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), value)
+
+	value, err = client.IncrBy(ctx, "counter", 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), value)
+
+	number, err := client.IncrByFloat(ctx, "counter", 0.5)
+	require.NoError(t, err)
+	assert.Equal(t, 11.5, number)
+
+	_, err = client.Incr(ctx, "counter")
+	assert.True(t, HasPrefix(err, PrefixErr))
+}
+
+func TestCommands_Ranges(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	length, err := client.Append(ctx, "some-key", "Hello")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), length)
+
+	length, err = client.Append(ctx, "some-key", " World")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), length)
+
+	value, err := client.GetRange(ctx, "some-key", 0, 4)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", value)
+
+	length, err = client.SetRange(ctx, "some-key", 6, "Redis")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), length)
+
+	value, err = client.GetRange(ctx, "some-key", -5, -1)
+	require.NoError(t, err)
+	assert.Equal(t, "Redis", value)
+}
+
+func TestCommands_OnPoolAndMultiplexer(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	pool := NewPool(PoolOptions{Options: Options{Address: server.Addr()}})
+	defer pool.Close()
+
+	_, err = pool.Set(context.Background(), "some-key", "pool", SetOptions{})
+	require.NoError(t, err)
+
+	value, err := pool.Get(context.Background(), "some-key")
+	require.NoError(t, err)
+	assert.Equal(t, "pool", value)
+
+	m, err := NewMultiplexer(context.Background(), Options{Address: server.Addr()})
+	require.NoError(t, err)
+	defer m.Close()
+
+	_, err = m.Set(context.Background(), "some-key", "multiplexer", SetOptions{})
+	require.NoError(t, err)
+
+	value, err = m.Get(context.Background(), "some-key")
+	require.NoError(t, err)
+	assert.Equal(t, "multiplexer", value)
+}
+
+func TestCommands_TransportErrors(t *testing.T) {
+	server := newFakeServer(t, func(w io.Writer, args []string) {
+		io.WriteString(w, "?not RESP\r\n")
+	})
+
+	client, err := Connect(context.Background(), server.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Get(context.Background(), "some-key")
+	assert.EqualError(t, err, "unknown RESP type '?', actual content in base64: [P25vdCBSRVNQ]")
+}
@@ -0,0 +1,194 @@
+package redis_client
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"strconv"
+)
+
+// TokenType is the RESP type of a Token, it is the byte that starts the message on the wire.
+type TokenType byte
+
+const (
+	TokenSimpleString   TokenType = typeSimpleString
+	TokenError          TokenType = typeErorr
+	TokenInteger        TokenType = typeInteger
+	TokenBulkString     TokenType = typeBulkString
+	TokenArray          TokenType = typeArray
+	TokenNull           TokenType = typeNull
+	TokenDouble         TokenType = typeDouble
+	TokenBoolean        TokenType = typeBoolean
+	TokenBigNumber      TokenType = typeBigNumber
+	TokenBlobError      TokenType = typeBlobError
+	TokenVerbatimString TokenType = typeVerbatimString
+	TokenMap            TokenType = typeMap
+	TokenSet            TokenType = typeSet
+	TokenAttribute      TokenType = typeAttribute
+	TokenPush           TokenType = typePush
+)
+
+// Token is a single RESP message without its children. for aggregates (arrays, maps, sets, attributes
+// and pushes) Length is the number of elements that follow, for maps and attributes it is the number of key
+// and value pairs, so twice as many tokens follow. for everything else Value is the content of the message,
+// like the bytes of a bulk string or the digits of an integer, and Length is its length. null replies
+// have a Length of -1 and a nil Value.
+//
+// Value is borrowed from the Reader, it is only valid until the next call to NextToken and must be copied
+// to be kept around.
+type Token struct {
+	Type   TokenType
+	Length int64
+	Value  []byte
+}
+
+// IsAggregate returns true if more tokens follow this one as its elements.
+func (t Token) IsAggregate() bool {
+	switch t.Type {
+	case TokenArray, TokenMap, TokenSet, TokenAttribute, TokenPush:
+		return t.Length > 0
+	}
+
+	return false
+}
+
+// IsNull returns true for RESP3 nulls and RESP2 null bulk strings and arrays.
+func (t Token) IsNull() bool {
+	return t.Length == -1
+}
+
+// Int64 parses Value as an integer without allocating.
+func (t Token) Int64() (int64, error) {
+	value, ok := parseInt(t.Value)
+	if !ok {
+		return 0, fmt.Errorf("token is not an integer: %q", t.Value)
+	}
+
+	return value, nil
+}
+
+// NextToken reads the next RESP message from the stream without decoding it into a Result, the low level
+// alternative to Read for hot paths that want to decode replies straight into their own buffers without
+// allocating. aggregates are not read as a whole, their elements are the next tokens, so a reply for
+// `MGET a b` is an array token with a Length of 2 followed by two bulk string tokens.
+//
+// Token values are borrowed from the reader and are only valid until the next call.
+func (r *Reader) NextToken() (Token, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return Token{}, err
+	}
+
+	tokenType := TokenType(line[0])
+
+	switch tokenType {
+	case TokenSimpleString, TokenError, TokenInteger, TokenDouble, TokenBoolean, TokenBigNumber:
+		return Token{Type: tokenType, Length: int64(len(line) - 1), Value: line[1:]}, nil
+	case TokenNull:
+		return Token{Type: tokenType, Length: -1}, nil
+	case TokenArray, TokenMap, TokenSet, TokenAttribute, TokenPush:
+		length, ok := parseInt(line[1:])
+		if !ok || length < -1 {
+			return Token{}, fmt.Errorf("invalid length: %v", string(line))
+		}
+
+		return Token{Type: tokenType, Length: length}, nil
+	case TokenBulkString, TokenBlobError, TokenVerbatimString:
+		length, ok := parseInt(line[1:])
+		if !ok || length < -1 {
+			return Token{}, fmt.Errorf("invalid length: %v", string(line))
+		}
+
+		if length == -1 {
+			return Token{Type: tokenType, Length: -1}, nil
+		}
+
+		value, err := r.borrowBulk(length)
+		if err != nil {
+			return Token{}, err
+		}
+
+		return Token{Type: tokenType, Length: length, Value: value}, nil
+	}
+
+	return Token{}, fmt.Errorf("unknown RESP type %q, actual content in base64: [%v]", line[0], base64.RawStdEncoding.EncodeToString(line))
+}
+
+// borrowBulk reads a bulk value and its \r\n. values that fit the bufio.Reader buffer are returned straight
+// from it, bigger ones are read into the reusable line buffer.
+func (r *Reader) borrowBulk(length int64) ([]byte, error) {
+	if length > r.maxBulkLength {
+		return nil, fmt.Errorf("bulk string of %v bytes is bigger than the max length of %v bytes", length, r.maxBulkLength)
+	}
+
+	total := int(length) + len(separator)
+
+	var value []byte
+	if total <= r.reader.Size() {
+		peeked, err := r.reader.Peek(total)
+		if err != nil {
+			return nil, errors.Wrapf(unexpectedEOF(err), "failed to read bulk string with %v bytes", length)
+		}
+
+		r.reader.Discard(total)
+		value = peeked
+	} else {
+		// the line buffer grows as the data arrives, so a length header alone can't allocate the whole value
+		value = r.line[:0]
+		for len(value) < total {
+			chunk := total - len(value)
+			if chunk > maxBulkCapacity {
+				chunk = maxBulkCapacity
+			}
+
+			start := len(value)
+			value = append(value, make([]byte, chunk)...)
+			if _, err := io.ReadFull(r.reader, value[start:]); err != nil {
+				return nil, errors.Wrapf(unexpectedEOF(err), "failed to read bulk string with %v bytes", length)
+			}
+		}
+
+		r.line = value
+	}
+
+	if value[length] != '\r' || value[length+1] != '\n' {
+		return nil, fmt.Errorf("bulk string does not end with \\r\\n, actual content in base64: [%v]", base64.RawStdEncoding.EncodeToString(value[length:]))
+	}
+
+	return value[:length], nil
+}
+
+// parseInt parses a base 10 integer without the allocations strconv makes for its errors.
+func parseInt(value []byte) (int64, bool) {
+	if len(value) == 0 {
+		return 0, false
+	}
+
+	digits := value
+	negative := value[0] == '-'
+	if negative || value[0] == '+' {
+		digits = value[1:]
+	}
+
+	// 18 digits always fit an int64, anything longer goes to strconv to check for overflows
+	if len(digits) == 0 || len(digits) > 18 {
+		result, err := strconv.ParseInt(string(value), 10, 64)
+		return result, err == nil
+	}
+
+	var result int64
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+
+		result = result*10 + int64(c-'0')
+	}
+
+	if negative {
+		result = -result
+	}
+
+	return result, true
+}
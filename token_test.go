@@ -0,0 +1,207 @@
+package redis_client
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReader_NextToken(t *testing.T) {
+	tt := []struct {
+		name   string
+		input  string
+		tokens []Token
+		err    string
+	}{
+		{
+			name:  "simple types",
+			input: "+OK\r\n-ERR failed\r\n:-10\r\n,3.14\r\n#t\r\n(3492890328409238509324850943850943825024385\r\n_\r\n",
+			tokens: []Token{
+				{Type: TokenSimpleString, Length: 2, Value: []byte("OK")},
+				{Type: TokenError, Length: 10, Value: []byte("ERR failed")},
+				{Type: TokenInteger, Length: 3, Value: []byte("-10")},
+				{Type: TokenDouble, Length: 4, Value: []byte("3.14")},
+				{Type: TokenBoolean, Length: 1, Value: []byte("t")},
+				{Type: TokenBigNumber, Length: 43, Value: []byte("3492890328409238509324850943850943825024385")},
+				{Type: TokenNull, Length: -1},
+			},
+		},
+		{
+			name:  "bulk strings",
+			input: "$5\r\nhello\r\n$0\r\n\r\n$-1\r\n!10\r\nERR failed\r\n=7\r\ntxt:abc\r\n",
+			tokens: []Token{
+				{Type: TokenBulkString, Length: 5, Value: []byte("hello")},
+				{Type: TokenBulkString, Length: 0, Value: []byte{}},
+				{Type: TokenBulkString, Length: -1},
+				{Type: TokenBlobError, Length: 10, Value: []byte("ERR failed")},
+				{Type: TokenVerbatimString, Length: 7, Value: []byte("txt:abc")},
+			},
+		},
+		{
+			name:  "aggregates",
+			input: "*2\r\n$1\r\na\r\n%1\r\n+key\r\n:1\r\n*-1\r\n~0\r\n",
+			tokens: []Token{
+				{Type: TokenArray, Length: 2},
+				{Type: TokenBulkString, Length: 1, Value: []byte("a")},
+				{Type: TokenMap, Length: 1},
+				{Type: TokenSimpleString, Length: 3, Value: []byte("key")},
+				{Type: TokenInteger, Length: 1, Value: []byte("1")},
+				{Type: TokenArray, Length: -1},
+				{Type: TokenSet, Length: 0},
+			},
+		},
+		{
+			name:  "invalid length",
+			input: "*x\r\n",
+			err:   "invalid length: *x",
+		},
+		{
+			name:  "bulk string without separator",
+			input: "$2\r\nabcd\r\n",
+			err:   "bulk string does not end with \\r\\n, actual content in base64: [Y2Q]",
+		},
+		{
+			name:  "bulk string cut short",
+			input: "$10\r\nabc",
+			err:   "failed to read bulk string with 10 bytes: unexpected EOF",
+		},
+		{
+			name:  "unknown type",
+			input: "?what\r\n",
+			err:   "unknown RESP type '?', actual content in base64: [P3doYXQ]",
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			reader := NewReader(strings.NewReader(ts.input))
+
+			for _, expected := range ts.tokens {
+				token, err := reader.NextToken()
+				require.NoError(t, err)
+				assert.Equal(t, expected, token)
+			}
+
+			_, err := reader.NextToken()
+			if ts.err != "" {
+				assert.EqualError(t, err, ts.err)
+			} else {
+				assert.Equal(t, io.EOF, err)
+			}
+		})
+	}
+}
+
+func TestReader_NextTokenLargeValues(t *testing.T) {
+	large := strings.Repeat("a", defaultBufferLength*3)
+	input := "$" + strconv.Itoa(len(large)) + "\r\n" + large + "\r\n$3\r\nabc\r\n"
+
+	reader := NewReader(strings.NewReader(input))
+
+	token, err := reader.NextToken()
+	require.NoError(t, err)
+	assert.Equal(t, large, string(token.Value))
+
+	token, err = reader.NextToken()
+	require.NoError(t, err)
+	assert.Equal(t, "abc", string(token.Value))
+
+	reader = NewReaderLimit(strings.NewReader(input), 10)
+	_, err = reader.NextToken()
+	assert.EqualError(t, err, "bulk string of 30420 bytes is bigger than the max length of 10 bytes")
+}
+
+func TestReader_NextTokenBulkGrowsWithData(t *testing.T) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	// a length just under the default limit that is never followed by the data
+	_, err := NewReader(strings.NewReader("$536870911\r\nabc")).NextToken()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	runtime.ReadMemStats(&after)
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(64*1024*1024))
+
+	value := strings.Repeat("a", maxBulkCapacity*3)
+	token, err := NewReader(strings.NewReader("$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n")).NextToken()
+	require.NoError(t, err)
+	assert.Equal(t, value, string(token.Value))
+}
+
+func TestToken_Int64(t *testing.T) {
+	tt := []struct {
+		input  string
+		result int64
+		err    string
+	}{
+		{input: "0", result: 0},
+		{input: "-10", result: -10},
+		{input: "+10", result: 10},
+		{input: "9223372036854775807", result: math.MaxInt64},
+		{input: "-9223372036854775808", result: math.MinInt64},
+		{input: "9223372036854775808", err: "token is not an integer: \"9223372036854775808\""},
+		{input: "-", err: "token is not an integer: \"-\""},
+		{input: "1x", err: "token is not an integer: \"1x\""},
+		{input: "", err: "token is not an integer: \"\""},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.input, func(t *testing.T) {
+			value, err := Token{Type: TokenInteger, Value: []byte(ts.input)}.Int64()
+			if ts.err != "" {
+				assert.EqualError(t, err, ts.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, ts.result, value)
+		})
+	}
+}
+
+func TestToken_Predicates(t *testing.T) {
+	assert.True(t, Token{Type: TokenArray, Length: 2}.IsAggregate())
+	assert.False(t, Token{Type: TokenArray, Length: 0}.IsAggregate())
+	assert.False(t, Token{Type: TokenBulkString, Length: 2}.IsAggregate())
+	assert.True(t, Token{Type: TokenArray, Length: -1}.IsNull())
+	assert.True(t, Token{Type: TokenNull, Length: -1}.IsNull())
+	assert.False(t, Token{Type: TokenBulkString, Length: 0}.IsNull())
+}
+
+// decodeMGET reads an MGET reply into values, reusing the slices that are already there.
+func decodeMGET(reader *Reader, values [][]byte) ([][]byte, error) {
+	token, err := reader.NextToken()
+	if err != nil {
+		return nil, err
+	}
+
+	values = values[:0]
+	for x := int64(0); x < token.Length; x++ {
+		element, err := reader.NextToken()
+		if err != nil {
+			return nil, err
+		}
+
+		if x < int64(cap(values)) {
+			values = values[:x+1]
+			values[x] = append(values[x][:0], element.Value...)
+		} else {
+			values = append(values, append([]byte(nil), element.Value...))
+		}
+	}
+
+	return values, nil
+}
+
+func TestReader_NextTokenDecodesIntoBuffers(t *testing.T) {
+	input := "*3\r\n$3\r\none\r\n$3\r\ntwo\r\n$5\r\nthree\r\n"
+
+	values, err := decodeMGET(NewReader(strings.NewReader(input)), nil)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("one"), []byte("two"), []byte("three")}, values)
+}
@@ -0,0 +1,170 @@
+package redis_client
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxWatchRetries is how many times Watch runs a transaction that keeps being aborted
+	maxWatchRetries = 10
+)
+
+var (
+	// ErrTxAborted is returned when EXEC replies with a null array because a watched key was changed.
+	ErrTxAborted = errors.New("redis: transaction aborted, a watched key has changed")
+)
+
+// Tx is a MULTI/EXEC transaction. commands are queued locally and only sent when Exec is called, with MULTI
+// before them and EXEC after them, all in a single write. commands sent with Do run right away, which is how
+// values are read after WATCH and before the transaction starts.
+type Tx struct {
+	client   *Client
+	commands [][]interface{}
+	watching bool
+}
+
+// Tx creates a transaction on this connection.
+func (c *Client) Tx() *Tx {
+	return &Tx{
+		client: c,
+	}
+}
+
+// Watch sends WATCH for the keys, if any of them changes before Exec the transaction is aborted.
+func (t *Tx) Watch(ctx context.Context, keys ...string) error {
+	args := make([]interface{}, 0, len(keys)+1)
+	args = append(args, "WATCH")
+	for _, key := range keys {
+		args = append(args, key)
+	}
+
+	if err := t.client.doOK(ctx, args); err != nil {
+		return err
+	}
+
+	t.watching = true
+	return nil
+}
+
+// Unwatch sends UNWATCH, forgetting all watched keys.
+func (t *Tx) Unwatch(ctx context.Context) error {
+	if err := t.client.doOK(ctx, []interface{}{"UNWATCH"}); err != nil {
+		return err
+	}
+
+	t.watching = false
+	return nil
+}
+
+// Do executes a command right away, outside of the transaction.
+func (t *Tx) Do(ctx context.Context, args ...interface{}) (*Result, error) {
+	return t.client.Do(ctx, args...)
+}
+
+// Queue adds a command to the transaction, nothing is sent until Exec is called.
+func (t *Tx) Queue(args ...interface{}) {
+	t.commands = append(t.commands, args)
+}
+
+// Discard drops all queued commands.
+func (t *Tx) Discard() {
+	t.commands = nil
+}
+
+// Exec sends MULTI, the queued commands and EXEC and returns a result for each queued command. errors
+// executing each command are on their results. if a watched key has changed it returns ErrTxAborted and if
+// a command fails to be queued (like a command with the wrong number of arguments) the whole transaction
+// is discarded by the server and the error for the command is returned.
+func (t *Tx) Exec(ctx context.Context) ([]*Result, error) {
+	queued := t.commands
+	t.commands = nil
+	t.watching = false
+
+	commands := make([][]interface{}, 0, len(queued)+2)
+	commands = append(commands, []interface{}{"MULTI"})
+	commands = append(commands, queued...)
+	commands = append(commands, []interface{}{"EXEC"})
+
+	for x, args := range queued {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("command %v on transaction is empty", x)
+		}
+	}
+
+	replies, err := t.client.pipeline(ctx, commands)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := replies[0].Err(); err != nil {
+		return nil, errors.Wrap(err, "MULTI failed")
+	}
+
+	// every command gets a QUEUED reply or an error if it could not be queued
+	for x, reply := range replies[1 : len(replies)-1] {
+		if err := reply.Err(); err != nil {
+			return nil, errors.Wrapf(err, "transaction discarded, command %v (%v) failed", x, queued[x][0])
+		}
+	}
+
+	exec := replies[len(replies)-1]
+	if err := exec.Err(); err != nil {
+		return nil, errors.Wrap(err, "EXEC failed")
+	}
+
+	if exec.Content() == nil {
+		return nil, ErrTxAborted
+	}
+
+	results, err := exec.Results()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid EXEC reply")
+	}
+
+	if len(results) != len(queued) {
+		return nil, fmt.Errorf("EXEC returned %v replies but %v commands were queued", len(results), len(queued))
+	}
+
+	return results, nil
+}
+
+// Watch runs a read-modify-write transaction: it sends WATCH for the keys and calls fn, that reads the values
+// it needs with tx.Do and then queues and executes its writes with tx.Queue and tx.Exec. if a watched key
+// changes before EXEC the whole thing runs again, up to 10 times, before giving up with ErrTxAborted.
+// any other error returned by fn stops it right away and is returned.
+func (c *Client) Watch(ctx context.Context, fn func(tx *Tx) error, keys ...string) error {
+	for attempt := 0; attempt < maxWatchRetries; attempt++ {
+		tx := c.Tx()
+		if err := tx.Watch(ctx, keys...); err != nil {
+			return err
+		}
+
+		err := fn(tx)
+
+		// fn might have returned before calling Exec, WATCH would still be active for the next commands
+		if tx.watching && !c.Broken() {
+			if unwatchErr := tx.Unwatch(ctx); unwatchErr != nil && err == nil {
+				err = unwatchErr
+			}
+		}
+
+		if !errors.Is(err, ErrTxAborted) {
+			return err
+		}
+	}
+
+	return errors.Wrapf(ErrTxAborted, "transaction aborted %v times", maxWatchRetries)
+}
+
+// Watch borrows a connection and calls Watch on it.
+func (p *Pool) Watch(ctx context.Context, fn func(tx *Tx) error, keys ...string) error {
+	client, err := p.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.Release(client)
+
+	return client.Watch(ctx, fn, keys...)
+}
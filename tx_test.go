@@ -0,0 +1,163 @@
+package redis_client
+
+import (
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func TestTx_Exec(t *testing.T) {
+	client, server := newTestClient(t)
+
+	tx := client.Tx()
+	tx.Queue("SET", "some-key", "some-value")
+	tx.Queue("LPUSH", "some-key", "value")
+	tx.Queue("INCR", "counter")
+
+	results, err := tx.Exec(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, "OK", results[0].Content())
+	assert.EqualError(t, results[1].Err(), "WRONGTYPE Operation against a key holding the wrong kind of value")
+	assert.Equal(t, int64(1), results[2].Content())
+
+	value, err := server.Get("some-key")
+	require.NoError(t, err)
+	assert.Equal(t, "some-value", value)
+}
+
+func TestTx_ExecDiscarded(t *testing.T) {
+	client, server := newTestClient(t)
+
+	tx := client.Tx()
+	tx.Queue("SET", "some-key", "some-value")
+	tx.Queue("GET")
+
+	_, err := tx.Exec(context.Background())
+	assert.EqualError(t, err, "transaction discarded, command 1 (GET) failed: ERR wrong number of arguments for 'get' command")
+	assert.False(t, server.Exists("some-key"))
+
+	// the connection is still good
+	result, err := client.Do(context.Background(), "PING")
+	require.NoError(t, err)
+	assert.Equal(t, "PONG", result.Content())
+}
+
+func TestTx_ExecAborted(t *testing.T) {
+	client, server := newTestClient(t)
+
+	tx := client.Tx()
+	require.NoError(t, tx.Watch(context.Background(), "some-key"))
+
+	require.NoError(t, server.Set("some-key", "changed"))
+
+	tx.Queue("SET", "some-key", "some-value")
+	_, err := tx.Exec(context.Background())
+	assert.Equal(t, ErrTxAborted, err)
+
+	value, err := server.Get("some-key")
+	require.NoError(t, err)
+	assert.Equal(t, "changed", value)
+}
+
+func TestClient_Watch(t *testing.T) {
+	client, server := newTestClient(t)
+	require.NoError(t, server.Set("counter", "10"))
+
+	attempts := 0
+	err := client.Watch(context.Background(), func(tx *Tx) error {
+		attempts++
+
+		result, err := tx.Do(context.Background(), "GET", "counter")
+		if err != nil {
+			return err
+		}
+
+		value, _, err := result.String()
+		if err != nil {
+			return err
+		}
+
+		counter, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+
+		// someone else changes the key on the first attempt
+		if attempts == 1 {
+			server.Set("counter", "20")
+		}
+
+		tx.Queue("SET", "counter", strconv.Itoa(counter*2))
+		_, err = tx.Exec(context.Background())
+		return err
+	}, "counter")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, attempts)
+
+	value, err := server.Get("counter")
+	require.NoError(t, err)
+	assert.Equal(t, "40", value)
+}
+
+func TestClient_WatchGivesUp(t *testing.T) {
+	client, server := newTestClient(t)
+
+	attempts := 0
+	err := client.Watch(context.Background(), func(tx *Tx) error {
+		attempts++
+		server.Set("some-key", strconv.Itoa(attempts))
+
+		tx.Queue("SET", "some-key", "mine")
+		_, err := tx.Exec(context.Background())
+		return err
+	}, "some-key")
+
+	assert.True(t, errors.Is(err, ErrTxAborted))
+	assert.EqualError(t, err, "transaction aborted 10 times: redis: transaction aborted, a watched key has changed")
+	assert.Equal(t, maxWatchRetries, attempts)
+}
+
+func TestClient_WatchWithoutExec(t *testing.T) {
+	client, server := newTestClient(t)
+
+	failure := errors.New("not today")
+	err := client.Watch(context.Background(), func(tx *Tx) error {
+		return failure
+	}, "some-key")
+	assert.Equal(t, failure, err)
+
+	// the key is not being watched anymore so changing it doesn't abort the next transaction
+	require.NoError(t, server.Set("some-key", "changed"))
+
+	tx := client.Tx()
+	tx.Queue("SET", "some-key", "mine")
+	_, err = tx.Exec(context.Background())
+	require.NoError(t, err)
+}
+
+func TestPool_Watch(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	pool := NewPool(PoolOptions{Options: Options{Address: server.Addr()}})
+	defer pool.Close()
+
+	err = pool.Watch(context.Background(), func(tx *Tx) error {
+		tx.Queue("SET", "some-key", "some-value")
+		_, err := tx.Exec(context.Background())
+		return err
+	}, "some-key")
+	require.NoError(t, err)
+
+	value, err := server.Get("some-key")
+	require.NoError(t, err)
+	assert.Equal(t, "some-value", value)
+}
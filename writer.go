@@ -1,116 +1,454 @@
 package redis_client
 
 import (
-	"encoding/base64"
+	"bytes"
+	"encoding"
 	"fmt"
 	"github.com/pkg/errors"
 	"io"
+	"math"
+	"net"
+	"reflect"
+	"sort"
 	"strconv"
+	"time"
 )
 
+const (
+	// largeValueLength is the size from which bulk strings are not copied into the buffer, they are kept
+	// as they are and sent with the buffered bytes in a single vectored write by Flush.
+	largeValueLength = 32 * 1024
+	// maxPendingLength is how much can be buffered before the next command flushes what is already there
+	maxPendingLength = 1024 * 1024
+	// maxRetainedLength is the biggest buffer kept for reuse after a flush
+	maxRetainedLength = 64 * 1024
+)
+
+// Writer encodes RESP messages into a buffer that is only sent to the underlying io.Writer when Flush is
+// called, so a command or a whole pipeline goes out in a single write. bulk strings bigger than 32KB are
+// not copied, they are sent as they are together with the buffered bytes using net.Buffers, which means
+// they must not be changed until Flush returns.
+//
+// commands that fail to be encoded leave nothing on the buffer. if more than 1MB is already buffered when
+// a new command or array is written, the buffered bytes are flushed first.
 type Writer struct {
-	writer io.Writer
+	writer     io.Writer
+	buffer     []byte
+	chunks     [][]byte
+	chunkStart int
+	// flushing is consumed by net.Buffers.WriteTo, it is a field so flushing doesn't allocate
+	flushing net.Buffers
+	scratch  [64]byte
 }
 
 func NewWriter(w io.Writer) *Writer {
 	return &Writer{
 		writer: w,
+		buffer: make([]byte, 0, defaultBufferLength),
 	}
 }
 
-func (w *Writer) write(messageType byte, contents ...[]byte) error {
-	if _, err := w.writer.Write([]byte{messageType}); err != nil {
-		return errors.Wrapf(err, "failed to write message type: %v", messageType)
+// Buffered returns how many bytes are waiting to be flushed.
+func (w *Writer) Buffered() int {
+	total := len(w.buffer) - w.chunkStart
+	for _, chunk := range w.chunks {
+		total += len(chunk)
 	}
 
-	for _, b := range contents {
-		if _, err := w.writer.Write(b); err != nil {
-			return errors.Wrapf(err, "failed to write bytes, content in base64: [%v]", base64.RawStdEncoding.EncodeToString(b))
-		}
+	return total
+}
+
+// Flush sends everything that was buffered to the underlying io.Writer.
+func (w *Writer) Flush() error {
+	if len(w.buffer) > w.chunkStart {
+		w.chunks = append(w.chunks, w.buffer[w.chunkStart:])
+	}
+
+	var err error
+	switch len(w.chunks) {
+	case 0:
+	case 1:
+		_, err = w.writer.Write(w.chunks[0])
+	default:
+		w.flushing = w.chunks
+		_, err = w.flushing.WriteTo(w.writer)
+		w.flushing = nil
+	}
+
+	w.reset()
+
+	if err != nil {
+		return errors.Wrap(err, "failed to flush buffered commands")
 	}
 
 	return nil
 }
 
+// reset drops everything that is buffered and the references to large values.
+func (w *Writer) reset() {
+	for x := range w.chunks {
+		w.chunks[x] = nil
+	}
+
+	w.chunks = w.chunks[:0]
+	w.chunkStart = 0
+
+	if cap(w.buffer) > maxRetainedLength {
+		w.buffer = make([]byte, 0, defaultBufferLength)
+	} else {
+		w.buffer = w.buffer[:0]
+	}
+}
+
+// writerMark is a position on the buffer that can be restored if encoding a message fails.
+type writerMark struct {
+	length     int
+	chunks     int
+	chunkStart int
+}
+
+// begin is called before a new top level message, it flushes if too much is buffered and returns
+// where the message starts.
+func (w *Writer) begin() (writerMark, error) {
+	if w.Buffered() > maxPendingLength {
+		if err := w.Flush(); err != nil {
+			return writerMark{}, err
+		}
+	}
+
+	return writerMark{
+		length:     len(w.buffer),
+		chunks:     len(w.chunks),
+		chunkStart: w.chunkStart,
+	}, nil
+}
+
+// rollback drops everything written after the mark.
+func (w *Writer) rollback(mark writerMark) {
+	for x := mark.chunks; x < len(w.chunks); x++ {
+		w.chunks[x] = nil
+	}
+
+	w.buffer = w.buffer[:mark.length]
+	w.chunks = w.chunks[:mark.chunks]
+	w.chunkStart = mark.chunkStart
+}
+
+func (w *Writer) writeHeader(messageType byte, length int64) {
+	w.buffer = append(w.buffer, messageType)
+	w.buffer = strconv.AppendInt(w.buffer, length, 10)
+	w.buffer = append(w.buffer, separator...)
+}
+
+// writeRaw adds bytes that are already encoded, large ones are kept as they are until Flush.
+func (w *Writer) writeRaw(value []byte) {
+	if len(value) < largeValueLength {
+		w.buffer = append(w.buffer, value...)
+		return
+	}
+
+	if len(w.buffer) > w.chunkStart {
+		w.chunks = append(w.chunks, w.buffer[w.chunkStart:])
+	}
+
+	w.chunks = append(w.chunks, value)
+	w.chunkStart = len(w.buffer)
+}
+
 func (w *Writer) WriteBulkString(value []byte) error {
-	return w.write(
-		typeBulkString,
-		[]byte(strconv.FormatInt(int64(len(value)), 10)),
-		separator,
-		value,
-		separator,
-	)
+	w.writeHeader(typeBulkString, int64(len(value)))
+	w.writeRaw(value)
+	w.buffer = append(w.buffer, separator...)
+	return nil
+}
+
+// writeBulkStringFrom writes a string without converting it to a []byte first.
+func (w *Writer) writeBulkStringFrom(value string) {
+	w.writeHeader(typeBulkString, int64(len(value)))
+	w.buffer = append(w.buffer, value...)
+	w.buffer = append(w.buffer, separator...)
 }
 
 // WriteNil writes a nil bulk string
 func (w *Writer) WriteNil() error {
-	return w.write(typeBulkString, []byte("-1"), separator)
+	w.writeHeader(typeBulkString, -1)
+	return nil
 }
 
 func (w *Writer) WriteInt64(v int64) error {
-	return w.write(
-		typeInteger,
-		[]byte(strconv.FormatInt(v, 10)),
-		separator)
+	w.writeHeader(typeInteger, v)
+	return nil
 }
 
-// WriteArray writes an array that contains int8 to int64, strings, []byte, []interface{} or nil.
-// Any other values inside the array will cause this method to return an error.
+// WriteArray writes an array that contains ints, uints, strings, []byte, []interface{} or nil. signed ints are
+// written as integers, nested []interface{} as nested arrays and any other type supported by WriteCommand is
+// written as a bulk string. uints are always bulk strings, as not all of them fit a RESP integer. any other
+// values inside the array will cause this method to return an error.
 func (w *Writer) WriteArray(values []interface{}) error {
-	if values == nil {
-		return w.write(typeArray, []byte("-1"), separator)
+	mark, err := w.begin()
+	if err != nil {
+		return err
 	}
 
-	if err := w.write(
-		typeArray,
-		[]byte(strconv.FormatInt(int64(len(values)), 10)),
-		separator,
-	); err != nil {
+	if err := w.writeArray(values); err != nil {
+		w.rollback(mark)
 		return err
 	}
 
+	return nil
+}
+
+func (w *Writer) writeArray(values []interface{}) error {
+	if values == nil {
+		w.writeHeader(typeArray, -1)
+		return nil
+	}
+
+	w.writeHeader(typeArray, int64(len(values)))
+
 	for _, v := range values {
 		switch t := v.(type) {
 		case int8:
-			if err := w.WriteInt64(int64(t)); err != nil {
-				return err
-			}
+			w.WriteInt64(int64(t))
 		case int16:
-			if err := w.WriteInt64(int64(t)); err != nil {
-				return err
-			}
+			w.WriteInt64(int64(t))
 		case int:
-			if err := w.WriteInt64(int64(t)); err != nil {
-				return err
-			}
+			w.WriteInt64(int64(t))
 		case int32:
-			if err := w.WriteInt64(int64(t)); err != nil {
-				return err
-			}
+			w.WriteInt64(int64(t))
 		case int64:
-			if err := w.WriteInt64(t); err != nil {
-				return err
-			}
+			w.WriteInt64(t)
 		case string:
-			if err := w.WriteBulkString([]byte(t)); err != nil {
-				return err
-			}
-		case []byte:
-			if err := w.WriteBulkString(t); err != nil {
-				return err
-			}
+			w.writeBulkStringFrom(t)
 		case []interface{}:
-			if err := w.WriteArray(t); err != nil {
+			if err := w.writeArray(t); err != nil {
 				return err
 			}
 		case nil:
-			if err := w.WriteNil(); err != nil {
+			w.WriteNil()
+		default:
+			arg, err := formatArg(v)
+			if err != nil {
 				return err
 			}
-		default:
-			return fmt.Errorf("unsupported type: the value [%#v] is not supported by this client, supported types are int8 to int64, strings, []byte, nil, and []interface{} of these same types", v)
+
+			w.WriteBulkString(arg)
 		}
 	}
 
 	return nil
 }
+
+// WriteCommand writes a command as an array of bulk strings, the only format redis accepts for commands.
+// arguments can be:
+//
+//   - strings and []byte, sent as they are
+//   - ints, uints and floats, formatted the way redis parses them, with infinities as inf and -inf
+//   - bools, sent as 1 and 0
+//   - time.Duration, sent in milliseconds, so it can be used with PX, PEXPIRE and other millisecond arguments,
+//     durations under a millisecond other than 0 fail instead of being sent as 0
+//   - time.Time, sent as a RFC 3339 timestamp, use Unix() on it for EXPIREAT
+//   - encoding.BinaryMarshaler, encoding.TextMarshaler and fmt.Stringer
+//   - slices and arrays, like []string or []interface{}, flattened into one argument per element
+//   - maps, like map[string]interface{}, flattened into key and value arguments sorted by key
+//
+// nil and any other type cause an error and nothing is written.
+func (w *Writer) WriteCommand(args ...interface{}) error {
+	mark, err := w.begin()
+	if err != nil {
+		return err
+	}
+
+	// commands made only of strings, []byte and ints, which are most of them, are written without allocating
+	if simpleArgs(args) {
+		w.writeHeader(typeArray, int64(len(args)))
+		for _, arg := range args {
+			w.writeSimpleArg(arg)
+		}
+
+		return nil
+	}
+
+	flattened, err := flattenArgs(make([][]byte, 0, len(args)), args)
+	if err != nil {
+		w.rollback(mark)
+		return err
+	}
+
+	w.writeHeader(typeArray, int64(len(flattened)))
+	for _, arg := range flattened {
+		w.WriteBulkString(arg)
+	}
+
+	return nil
+}
+
+// simpleArgs returns true if all args can be written with writeSimpleArg.
+func simpleArgs(args []interface{}) bool {
+	for _, arg := range args {
+		switch arg.(type) {
+		case string, []byte, int, int64:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func (w *Writer) writeSimpleArg(arg interface{}) {
+	switch t := arg.(type) {
+	case string:
+		w.writeBulkStringFrom(t)
+	case []byte:
+		w.WriteBulkString(t)
+	case int:
+		w.WriteBulkString(strconv.AppendInt(w.scratch[:0], int64(t), 10))
+	case int64:
+		w.WriteBulkString(strconv.AppendInt(w.scratch[:0], t, 10))
+	}
+}
+
+// flattenArgs formats the args, slices and maps are expanded into their elements.
+func flattenArgs(flattened [][]byte, args []interface{}) ([][]byte, error) {
+	for _, arg := range args {
+		var err error
+		flattened, err = flattenArg(flattened, arg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return flattened, nil
+}
+
+func flattenArg(flattened [][]byte, arg interface{}) ([][]byte, error) {
+	switch t := arg.(type) {
+	case []interface{}:
+		return flattenArgs(flattened, t)
+	case []string:
+		for _, value := range t {
+			flattened = append(flattened, []byte(value))
+		}
+
+		return flattened, nil
+	}
+
+	value, err := formatArg(arg)
+	if err == nil {
+		return append(flattened, value), nil
+	}
+
+	v := reflect.ValueOf(arg)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for x := 0; x < v.Len(); x++ {
+			flattened, err = flattenArg(flattened, v.Index(x).Interface())
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return flattened, nil
+	case reflect.Map:
+		entries := make([][2][]byte, 0, v.Len())
+
+		iterator := v.MapRange()
+		for iterator.Next() {
+			key, err := formatArg(iterator.Key().Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := formatArg(iterator.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, [2][]byte{key, value})
+		}
+
+		// map iteration order is random, sorting makes the same map always produce the same command
+		sort.Slice(entries, func(i, j int) bool {
+			return bytes.Compare(entries[i][0], entries[j][0]) < 0
+		})
+
+		for _, entry := range entries {
+			flattened = append(flattened, entry[0], entry[1])
+		}
+
+		return flattened, nil
+	}
+
+	return nil, err
+}
+
+// formatArg formats a single argument for a command.
+func formatArg(arg interface{}) ([]byte, error) {
+	switch t := arg.(type) {
+	case string:
+		return []byte(t), nil
+	case []byte:
+		return t, nil
+	case int:
+		return strconv.AppendInt(nil, int64(t), 10), nil
+	case int8:
+		return strconv.AppendInt(nil, int64(t), 10), nil
+	case int16:
+		return strconv.AppendInt(nil, int64(t), 10), nil
+	case int32:
+		return strconv.AppendInt(nil, int64(t), 10), nil
+	case int64:
+		return strconv.AppendInt(nil, t, 10), nil
+	case uint:
+		return strconv.AppendUint(nil, uint64(t), 10), nil
+	case uint8:
+		return strconv.AppendUint(nil, uint64(t), 10), nil
+	case uint16:
+		return strconv.AppendUint(nil, uint64(t), 10), nil
+	case uint32:
+		return strconv.AppendUint(nil, uint64(t), 10), nil
+	case uint64:
+		return strconv.AppendUint(nil, t, 10), nil
+	case float32:
+		return formatFloat(float64(t), 32)
+	case float64:
+		return formatFloat(t, 64)
+	case bool:
+		if t {
+			return []byte("1"), nil
+		}
+
+		return []byte("0"), nil
+	case time.Duration:
+		if t != 0 && t > -time.Millisecond && t < time.Millisecond {
+			return nil, fmt.Errorf("duration of %v is under a millisecond and would be sent as 0", t)
+		}
+
+		return strconv.AppendInt(nil, t.Milliseconds(), 10), nil
+	case time.Time:
+		return t.AppendFormat(nil, time.RFC3339Nano), nil
+	case encoding.BinaryMarshaler:
+		return t.MarshalBinary()
+	case encoding.TextMarshaler:
+		return t.MarshalText()
+	case fmt.Stringer:
+		return []byte(t.String()), nil
+	}
+
+	return nil, fmt.Errorf("unsupported type: the value [%#v] is not supported by this client, supported types are ints, uints, floats, bools, strings, []byte, time.Duration, time.Time, encoding.BinaryMarshaler, encoding.TextMarshaler, fmt.Stringer and slices and maps of these same types", arg)
+}
+
+// formatFloat formats floats the way redis parses them, infinities are inf and -inf and NaN is not accepted.
+func formatFloat(value float64, bitSize int) ([]byte, error) {
+	switch {
+	case math.IsInf(value, 1):
+		return []byte("inf"), nil
+	case math.IsInf(value, -1):
+		return []byte("-inf"), nil
+	case math.IsNaN(value):
+		return nil, errors.New("NaN can't be sent to redis")
+	}
+
+	return strconv.AppendFloat(nil, value, 'f', -1, bitSize), nil
+}
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"math"
+	"net"
 	"testing"
+	"time"
 )
 
 func TestWriter_WriteArray(t *testing.T) {
@@ -61,6 +64,32 @@ func TestWriter_WriteArray(t *testing.T) {
 			input:  nil,
 			output: nil,
 		},
+		{
+			name: "unsigned ints, floats and bools",
+			input: []interface{}{
+				uint8(8),
+				uint(10),
+				uint64(math.MaxUint64),
+				1.5,
+				math.Inf(1),
+				true,
+				time.Second,
+			},
+			output: []interface{}{
+				"8",
+				"10",
+				"18446744073709551615",
+				"1.5",
+				"inf",
+				"1",
+				"1000",
+			},
+		},
+		{
+			name:  "an unsupported type",
+			input: []interface{}{struct{}{}},
+			err:   "unsupported type: the value [struct {}{}] is not supported by this client, supported types are ints, uints, floats, bools, strings, []byte, time.Duration, time.Time, encoding.BinaryMarshaler, encoding.TextMarshaler, fmt.Stringer and slices and maps of these same types",
+		},
 	}
 
 	for _, ts := range tt {
@@ -71,8 +100,11 @@ func TestWriter_WriteArray(t *testing.T) {
 			err := writer.WriteArray(ts.input)
 			if ts.err != "" {
 				assert.EqualError(t, err, ts.err)
+				assert.Equal(t, 0, writer.Buffered())
 			} else {
 				require.NoError(t, err)
+				assert.Equal(t, 0, buffer.Len(), "nothing is written before Flush")
+				require.NoError(t, writer.Flush())
 
 				reader := NewReader(buffer)
 				result, err := reader.Read()
@@ -81,5 +113,177 @@ func TestWriter_WriteArray(t *testing.T) {
 			}
 		})
 	}
+}
+
+type binaryValue struct{}
+
+func (binaryValue) MarshalBinary() ([]byte, error) {
+	return []byte{0, 1, 2}, nil
+}
+
+func TestWriter_WriteCommand(t *testing.T) {
+	tt := []struct {
+		name   string
+		input  []interface{}
+		output []interface{}
+		err    string
+	}{
+		{
+			name:   "strings and bytes",
+			input:  []interface{}{"SET", []byte("some-key"), "some-value"},
+			output: []interface{}{"SET", "some-key", "some-value"},
+		},
+		{
+			name:   "numbers",
+			input:  []interface{}{int8(-8), 10, uint16(16), uint64(math.MaxUint64), float32(1.5), 0.1, math.Inf(1), math.Inf(-1)},
+			output: []interface{}{"-8", "10", "16", "18446744073709551615", "1.5", "0.1", "inf", "-inf"},
+		},
+		{
+			name: "bools, durations, times and marshalers",
+			input: []interface{}{
+				true,
+				false,
+				time.Millisecond * 1500,
+				time.Date(2021, 10, 5, 13, 30, 0, 500, time.UTC),
+				binaryValue{},
+				net.ParseIP("127.0.0.1"),
+				upperString("BR"),
+			},
+			output: []interface{}{
+				"1",
+				"0",
+				"1500",
+				"2021-10-05T13:30:00.0000005Z",
+				"\x00\x01\x02",
+				"127.0.0.1",
+				"br",
+			},
+		},
+		{
+			name:   "slices are flattened",
+			input:  []interface{}{"DEL", []string{"a", "b"}, []int{1, 2}, []interface{}{"c", []interface{}{"d"}}},
+			output: []interface{}{"DEL", "a", "b", "1", "2", "c", "d"},
+		},
+		{
+			name:   "maps are flattened sorted by key",
+			input:  []interface{}{"HSET", "some-key", map[string]interface{}{"name": "joe", "age": 30, "admin": true}},
+			output: []interface{}{"HSET", "some-key", "admin", "1", "age", "30", "name", "joe"},
+		},
+		{
+			name:  "NaN",
+			input: []interface{}{"INCRBYFLOAT", "some-key", math.NaN()},
+			err:   "NaN can't be sent to redis",
+		},
+		{
+			name:   "a zero duration",
+			input:  []interface{}{"BLPOP", "some-list", time.Duration(0)},
+			output: []interface{}{"BLPOP", "some-list", "0"},
+		},
+		{
+			name:  "a duration under a millisecond",
+			input: []interface{}{"SET", "some-key", "some-value", "PX", time.Microsecond * 500},
+			err:   "duration of 500µs is under a millisecond and would be sent as 0",
+		},
+		{
+			name:  "nil",
+			input: []interface{}{"SET", "some-key", nil},
+			err:   "unsupported type: the value [<nil>] is not supported by this client, supported types are ints, uints, floats, bools, strings, []byte, time.Duration, time.Time, encoding.BinaryMarshaler, encoding.TextMarshaler, fmt.Stringer and slices and maps of these same types",
+		},
+	}
+
+	for _, ts := range tt {
+		t.Run(ts.name, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+
+			writer := NewWriter(buffer)
+			err := writer.WriteCommand(ts.input...)
+			if ts.err != "" {
+				assert.EqualError(t, err, ts.err)
+				assert.Equal(t, 0, writer.Buffered())
+				return
+			}
+
+			require.NoError(t, err)
+			require.NoError(t, writer.Flush())
+
+			result, err := NewReader(buffer).Read()
+			require.NoError(t, err)
+			assert.Equal(t, ts.output, result.Content())
+		})
+	}
+}
+
+// countingWriter records every call to Write.
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.writes++
+	return c.Buffer.Write(p)
+}
+
+func TestWriter_Flush(t *testing.T) {
+	output := &countingWriter{}
+	writer := NewWriter(output)
+
+	large := bytes.Repeat([]byte("a"), largeValueLength)
+
+	require.NoError(t, writer.WriteCommand("SET", "some-key", "some-value"))
+	require.NoError(t, writer.WriteCommand("SET", "large-key", large))
+	require.NoError(t, writer.WriteCommand("GET", "some-key"))
+	assert.Equal(t, 0, output.writes)
+
+	// the large value is referenced, not copied
+	large[0] = 'b'
+
+	require.NoError(t, writer.Flush())
+	assert.Equal(t, 0, writer.Buffered())
+
+	reader := NewReader(output)
+	for _, expected := range []interface{}{
+		[]interface{}{"SET", "some-key", "some-value"},
+		[]interface{}{"SET", "large-key", string(large)},
+		[]interface{}{"GET", "some-key"},
+	} {
+		result, err := reader.Read()
+		require.NoError(t, err)
+		assert.Equal(t, expected, result.Content())
+	}
+
+	// flushing with nothing buffered doesn't write
+	writes := output.writes
+	require.NoError(t, writer.Flush())
+	assert.Equal(t, writes, output.writes)
+}
+
+func TestWriter_FlushesWhenFull(t *testing.T) {
+	output := &countingWriter{}
+	writer := NewWriter(output)
+
+	value := bytes.Repeat([]byte("a"), largeValueLength-1)
+	for writer.Buffered() <= maxPendingLength {
+		require.NoError(t, writer.WriteCommand("SET", "some-key", value))
+	}
+	assert.Equal(t, 0, output.writes)
+
+	require.NoError(t, writer.WriteCommand("PING"))
+	assert.Equal(t, 1, output.writes)
+	assert.Equal(t, 14, writer.Buffered())
+}
+
+func TestWriter_RollsBackFailedCommands(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer := NewWriter(buffer)
+
+	large := bytes.Repeat([]byte("a"), largeValueLength)
+
+	require.NoError(t, writer.WriteCommand("PING"))
+	assert.Error(t, writer.WriteCommand("SET", "some-key", large, math.NaN()))
+	assert.Error(t, writer.WriteArray([]interface{}{"SET", large, struct{}{}}))
+	assert.Equal(t, 14, writer.Buffered())
 
+	require.NoError(t, writer.Flush())
+	assert.Equal(t, "*1\r\n$4\r\nPING\r\n", buffer.String())
 }